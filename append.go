@@ -0,0 +1,112 @@
+package beson
+
+import (
+    "encoding/binary"
+    "math"
+
+    "beson/types"
+)
+
+// AppendSerialize appends the encoding of v to dst and returns the
+// extended buffer. It produces the same bytes as Serialize, but every
+// value is written in place: containers reserve their length prefix and
+// patch it once their children are written, so nothing is copied twice
+// whatever the nesting. Given a buffer large enough, the only allocation
+// left is the sorted key list of each *types.Map.
+func AppendSerialize(dst []byte, v types.RootType) []byte {
+    return appendContent(dst, v, DefaultRegistry)
+}
+
+func appendContent(dst []byte, data interface{}, reg *Registry) []byte {
+    data = marshaled(data)
+    t := reg.typeOf(data)
+    if t == KindInvalid {
+        return dst
+    }
+    dst = append(dst, byte(t >> 8), byte(t))
+    return appendData(dst, t, data, reg)
+}
+
+// appendData appends the payload of data, whose type is t. Types that are
+// rare or build their bytes anyway go through serializeData.
+func appendData(dst []byte, t Kind, data interface{}, reg *Registry) []byte {
+    switch t {
+    case KindNull, KindTrue, KindFalse:
+        return dst
+    case KindUInt8:
+        return append(dst, data.(*types.UInt8).Get())
+    case KindUInt16:
+        return binary.LittleEndian.AppendUint16(dst, data.(*types.UInt16).Get())
+    case KindUInt32:
+        return binary.LittleEndian.AppendUint32(dst, data.(*types.UInt32).Get())
+    case KindUInt64:
+        return binary.LittleEndian.AppendUint64(dst, data.(*types.UInt64).Get())
+    case KindInt8:
+        return append(dst, uint8(data.(*types.Int8).Get()))
+    case KindInt16:
+        return binary.LittleEndian.AppendUint16(dst, uint16(data.(*types.Int16).Get()))
+    case KindInt32:
+        return binary.LittleEndian.AppendUint32(dst, uint32(data.(*types.Int32).Get()))
+    case KindInt64:
+        return binary.LittleEndian.AppendUint64(dst, uint64(data.(*types.Int64).Get()))
+    case KindFloat32:
+        return binary.LittleEndian.AppendUint32(dst, math.Float32bits(data.(*types.Float32).Get()))
+    case KindFloat64:
+        return binary.LittleEndian.AppendUint64(dst, math.Float64bits(data.(*types.Float64).Get()))
+    case KindString:
+        str := data.(*types.String).Get()
+        dst = binary.LittleEndian.AppendUint32(dst, uint32(len(str)))
+        return append(dst, str...)
+    case KindBinary:
+        bs := data.(*types.Binary).ToBytes()
+        dst = binary.LittleEndian.AppendUint32(dst, uint32(len(bs)))
+        return append(dst, bs...)
+    case KindArray:
+        return appendSlice(dst, data.(*types.Slice), reg)
+    case KindMap:
+        return appendMap(dst, data, reg)
+    }
+    return append(dst, serializeData(t, data, reg)...)
+}
+
+func appendSlice(dst []byte, value *types.Slice, reg *Registry) []byte {
+    dst, lengthAt := reserveLength(dst)
+    for _, element := range value.Get() {
+        dst = appendContent(dst, element, reg)
+    }
+    return patchLength(dst, lengthAt)
+}
+
+func appendMap(dst []byte, data interface{}, reg *Registry) []byte {
+    dst, lengthAt := reserveLength(dst)
+    for _, key := range mapKeys(data) {
+        value := marshaled(mapValue(data, key))
+        t := reg.typeOf(value)
+        if t == KindInvalid {
+            // Unsupported values are left out along with their key, as
+            // appendSlice leaves out unsupported elements.
+            continue
+        }
+        dst = append(dst, byte(t >> 8), byte(t))
+        dst = appendShortString(dst, key)
+        dst = appendData(dst, t, value, reg)
+    }
+    return patchLength(dst, lengthAt)
+}
+
+func appendShortString(dst []byte, str string) []byte {
+    dst = binary.LittleEndian.AppendUint16(dst, uint16(len(str)))
+    return append(dst, str...)
+}
+
+// reserveLength appends room for a 4 byte length and returns its offset.
+func reserveLength(dst []byte) ([]byte, int) {
+    return append(dst, 0, 0, 0, 0), len(dst)
+}
+
+// patchLength writes at lengthAt the number of bytes that follow the
+// length.
+func patchLength(dst []byte, lengthAt int) []byte {
+    binary.LittleEndian.PutUint32(dst[lengthAt:], uint32(len(dst) - lengthAt - 4))
+    return dst
+}
@@ -1,6 +1,10 @@
 package beson
 
 import (
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "io/ioutil"
     "reflect"
     "testing"
 
@@ -9,30 +13,37 @@ import (
 
 var originData = map[string]types.RootType {
     "NULL":     nil,
-    "TRUE":     types.NewBool(true).(*types.Bool),
-    "FALSE":    types.NewBool(false).(*types.Bool),
-    "UINT8":    types.NewUInt8(2).(*types.UInt8),
-    "UINT16":   types.NewUInt16(2).(*types.UInt16),
-    "UINT32":   types.NewUInt32(2).(*types.UInt32),
-    "UINT64":   types.NewUInt64(2).(*types.UInt64),
+    "TRUE":     types.NewBool(true),
+    "FALSE":    types.NewBool(false),
+    "UINT8":    types.NewUInt8(2),
+    "UINT16":   types.NewUInt16(2),
+    "UINT32":   types.NewUInt32(2),
+    "UINT64":   types.NewUInt64(2),
     "UINT128":  types.NewUInt128("2", 10).(*types.UInt128),
-    "INT8":     types.NewInt8(-3).(*types.Int8),
-    "INT16":    types.NewInt16(-3).(*types.Int16),
-    "INT32":    types.NewInt32(-3).(*types.Int32),
-    "INT64":    types.NewInt64(-3).(*types.Int64),
+    "INT8":     types.NewInt8(-3),
+    "INT16":    types.NewInt16(-3),
+    "INT32":    types.NewInt32(-3),
+    "INT64":    types.NewInt64(-3),
     "INT128":   types.NewInt128("-3", 10).(*types.Int128),
-    "FLOAT32":  types.NewFloat32(0.456).(*types.Float32),
-    "FLOAT64":  types.NewFloat64(0.456).(*types.Float64),
-    "STRING":   types.NewString("Hello world").(*types.String),
+    "FLOAT32":  types.NewFloat32(0.456),
+    "FLOAT64":  types.NewFloat64(0.456),
+    "STRING":   types.NewString("Hello world"),
     "ARRAY":    types.NewSlice([]types.RootType { 
-        types.NewFloat32(0.456).(*types.Float32),
-        types.NewInt32(-3).(*types.Int32),
-    }).(*types.Slice),
+        types.NewFloat32(0.456),
+        types.NewInt32(-3),
+    }),
     "MAP":      types.NewMap(map[string]types.RootType { 
-        "apple":    types.NewUInt8(2).(*types.UInt8),
-        "banana":   types.NewBool(false).(*types.Bool),
-    }).(*types.Map),
+        "apple":    types.NewUInt8(2),
+        "banana":   types.NewBool(false),
+    }),
     "BINARY":   types.NewBinary(0).(*types.Binary).FromHex("0x2564877"),
+    "DATE":     types.NewDateFromMillis(1554249600123),
+    "OBJECTID": mustObjectIdFromHex("5ca40b80e4d1a3041f2c3b4a"),
+    "ARRAY_BUFFER":     types.NewArrayBuffer([]byte{ 1, 2, 3 }),
+    "INT16_ARRAY":      types.NewInt16Array([]int16{ -3, 258 }),
+    "FLOAT32_ARRAY":    types.NewFloat32Array([]float32{ 0.456 }),
+    "FLOAT64_ARRAY":    types.NewFloat64Array([]float64{}),
+    "SPECIAL_BUFFER":   types.NewSpecialBuffer(0x81, []byte{ 1, 2 }),
 }
 
 var serializedData = map[string][]byte {
@@ -55,6 +66,21 @@ var serializedData = map[string][]byte {
     "ARRAY":    []byte{ 6, 0, 12, 0, 0, 0, 4, 1, 213, 120, 233, 62, 2, 0, 253, 255, 255, 255 },
     "MAP":      []byte{ 9, 0, 20, 0, 0, 0, 3, 4, 5, 0, 97, 112, 112, 108, 101, 2, 1, 0, 6, 0, 98, 97, 110, 97, 110, 97 },
     "BINARY":   []byte{ 14, 0, 4, 0, 0, 0, 2, 86, 72, 119 },
+    "DATE":     []byte{ 12, 0, 0, 176, 199, 236, 7, 158, 118, 66 },
+    "OBJECTID": []byte{ 13, 0, 92, 164, 11, 128, 228, 209, 163, 4, 31, 44, 59, 74 },
+    "ARRAY_BUFFER":     []byte{ 15, 0, 3, 0, 0, 0, 1, 2, 3 },
+    "INT16_ARRAY":      []byte{ 15, 5, 4, 0, 0, 0, 253, 255, 2, 1 },
+    "FLOAT32_ARRAY":    []byte{ 15, 8, 4, 0, 0, 0, 213, 120, 233, 62 },
+    "FLOAT64_ARRAY":    []byte{ 15, 9, 0, 0, 0, 0 },
+    "SPECIAL_BUFFER":   []byte{ 15, 255, 3, 0, 0, 0, 0x81, 1, 2 },
+}
+
+func mustObjectIdFromHex(s string) *types.ObjectId {
+    id, err := types.ObjectIdFromHex(s)
+    if err != nil {
+        panic(err)
+    }
+    return id
 }
 
 func TestSerialize(t *testing.T) {
@@ -77,6 +103,13 @@ func TestSerialize(t *testing.T) {
     t.Run("ARRAY", testSerializeFunc(originData["ARRAY"], serializedData["ARRAY"]))
     t.Run("MAP", testSerializeFunc(originData["MAP"], serializedData["MAP"]))
     t.Run("BINARY", testSerializeFunc(originData["BINARY"], serializedData["BINARY"]))
+    t.Run("DATE", testSerializeFunc(originData["DATE"], serializedData["DATE"]))
+    t.Run("OBJECTID", testSerializeFunc(originData["OBJECTID"], serializedData["OBJECTID"]))
+    t.Run("ARRAY_BUFFER", testSerializeFunc(originData["ARRAY_BUFFER"], serializedData["ARRAY_BUFFER"]))
+    t.Run("INT16_ARRAY", testSerializeFunc(originData["INT16_ARRAY"], serializedData["INT16_ARRAY"]))
+    t.Run("FLOAT32_ARRAY", testSerializeFunc(originData["FLOAT32_ARRAY"], serializedData["FLOAT32_ARRAY"]))
+    t.Run("FLOAT64_ARRAY", testSerializeFunc(originData["FLOAT64_ARRAY"], serializedData["FLOAT64_ARRAY"]))
+    t.Run("SPECIAL_BUFFER", testSerializeFunc(originData["SPECIAL_BUFFER"], serializedData["SPECIAL_BUFFER"]))
 }
 
 func testSerializeFunc(data interface{}, expect []byte) func(*testing.T) {  
@@ -110,15 +143,97 @@ func TestDeserialize(t *testing.T) {
     t.Run("ARRAY", testDeserializeFunc(serializedData["ARRAY"], originData["ARRAY"]))
     t.Run("MAP", testDeserializeFunc(serializedData["MAP"], originData["MAP"]))
     t.Run("BINARY", testDeserializeFunc(serializedData["BINARY"], originData["BINARY"]))
+    t.Run("DATE", testDeserializeFunc(serializedData["DATE"], originData["DATE"]))
+    t.Run("OBJECTID", testDeserializeFunc(serializedData["OBJECTID"], originData["OBJECTID"]))
+    t.Run("ARRAY_BUFFER", testDeserializeFunc(serializedData["ARRAY_BUFFER"], originData["ARRAY_BUFFER"]))
+    t.Run("INT16_ARRAY", testDeserializeFunc(serializedData["INT16_ARRAY"], originData["INT16_ARRAY"]))
+    t.Run("FLOAT32_ARRAY", testDeserializeFunc(serializedData["FLOAT32_ARRAY"], originData["FLOAT32_ARRAY"]))
+    t.Run("FLOAT64_ARRAY", testDeserializeFunc(serializedData["FLOAT64_ARRAY"], originData["FLOAT64_ARRAY"]))
+    t.Run("SPECIAL_BUFFER", testDeserializeFunc(serializedData["SPECIAL_BUFFER"], originData["SPECIAL_BUFFER"]))
 }
 
-func testDeserializeFunc(ser []byte, expect interface{}) func(*testing.T) { 
+func testDeserializeFunc(ser []byte, expect types.RootType) func(*testing.T) { 
     return func(t *testing.T) {
         _, data := Deserialize(ser, 0)
-        if reflect.DeepEqual(data, expect) {
+        if types.Equal(data, expect, types.EqualOptions{}) {
             t.Log("Deserialize test passed.")
         } else {
             t.Error("Deserialize test failed.")
         }
     }
 }
+
+func TestDeserializeE(t *testing.T) {
+    t.Run("EMPTY", testDeserializeEFunc([]byte{}, ErrTruncated, 0))
+    t.Run("HEADER", testDeserializeEFunc([]byte{ 3 }, ErrTruncated, 0))
+    t.Run("UNKNOWN", testDeserializeEFunc([]byte{ 0x7f, 0x7f }, ErrUnknownType, 0))
+    t.Run("UINT32", testDeserializeEFunc([]byte{ 3, 0, 2, 0 }, ErrTruncated, 2))
+    t.Run("STRING_LENGTH", testDeserializeEFunc([]byte{ 5, 0, 11, 0 }, ErrTruncated, 2))
+    t.Run("STRING", testDeserializeEFunc([]byte{ 5, 0, 11, 0, 0, 0, 72, 101 }, ErrLengthOverflow, 2))
+    t.Run("BINARY", testDeserializeEFunc([]byte{ 14, 0, 255, 255, 255, 255, 2 }, ErrLengthOverflow, 2))
+    t.Run("MAP", testDeserializeEFunc([]byte{ 9, 0, 20, 0, 0, 0, 3, 4, 5, 0, 97, 112 }, ErrLengthOverflow, 2))
+    t.Run("MAP_KEY", testDeserializeEFunc([]byte{ 9, 0, 6, 0, 0, 0, 3, 4, 5, 0, 97, 112 }, ErrLengthOverflow, 8))
+    t.Run("INT16_ARRAY", testDeserializeEFunc([]byte{ 15, 5, 3, 0, 0, 0, 253, 255, 2 }, ErrInvalidLength, 2))
+    t.Run("SPECIAL_BUFFER", testDeserializeEFunc([]byte{ 15, 255, 0, 0, 0, 0 }, ErrTruncated, 6))
+    t.Run("ARRAY_CHILD", testDeserializeEFunc([]byte{ 6, 0, 4, 0, 0, 0, 2, 0, 253, 255, 255, 255 }, ErrTruncated, 8))
+}
+
+func testDeserializeEFunc(ser []byte, expect error, offset uint32) func(*testing.T) {
+    return func(t *testing.T) {
+        _, _, err := DeserializeE(ser, 0)
+        var derr *DeserializeError
+        if errors.As(err, &derr) && errors.Is(err, expect) && derr.Offset == int64(offset) {
+            t.Log("DeserializeE test passed.")
+        } else {
+            t.Errorf("DeserializeE test failed: %v", err)
+        }
+    }
+}
+
+// testdata/int256.json holds encodings shared with the other beson
+// implementations.
+func TestInt256Vectors(t *testing.T) {
+    content, err := ioutil.ReadFile("testdata/int256.json")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var vectors []struct {
+        Type    string  `json:"type"`
+        Value   string  `json:"value"`
+        Hex     string  `json:"hex"`
+    }
+    if err := json.Unmarshal(content, &vectors); err != nil {
+        t.Fatal(err)
+    }
+
+    for _, vector := range vectors {
+        expect, _ := hex.DecodeString(vector.Hex)
+        var origin types.RootType
+        if vector.Type == KindInt256.String() {
+            origin = types.NewInt256(vector.Value, 10)
+        } else {
+            origin = types.NewUInt256(vector.Value, 10)
+        }
+        t.Run(vector.Type + "(" + vector.Value + ")", testSerializeFunc(origin, expect))
+        t.Run(vector.Type + "(" + vector.Value + ")", testDeserializeInt256Func(expect, vector.Value))
+    }
+}
+
+func testDeserializeInt256Func(ser []byte, expect string) func(*testing.T) {
+    return func(t *testing.T) {
+        _, data, err := DeserializeE(ser, 0)
+        var actual string
+        switch value := data.(type) {
+        case *types.Int256:
+            actual, _ = value.ToString(10)
+        case *types.UInt256:
+            actual, _ = value.ToString(10)
+        }
+        if err == nil && actual == expect {
+            t.Log("Deserialize test passed.")
+        } else {
+            t.Errorf("Deserialize test failed: %s %v", actual, err)
+        }
+    }
+}
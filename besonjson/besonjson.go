@@ -0,0 +1,720 @@
+// Package besonjson converts beson values to JSON and back.
+//
+// In Plain mode values are mapped onto their closest JSON counterpart and
+// the exact beson type is lost: every integer becomes a JSON number,
+// BINARY an "0x..." string, DATE an RFC 3339 string and so on.
+//
+// In Extended mode every type without a native JSON representation is
+// wrapped in a single key object naming it, e.g. {"$int8":-3},
+// {"$uint128":"340282366920938463463374607431768211455"},
+// {"$binary":"0x0256"}, {"$special":{"subtype":1,"data":"0x0256"}} or
+// {"$float32":0.456}, so that a value survives a round trip unchanged.
+// Untagged numbers stand for FLOAT64. Integers of 64 bits and more are
+// written as decimal strings to keep their precision.
+// An object whose single key is a known tag is always read as that tag,
+// so a map holding a single key of that form is written escaped as
+// {"$map":{...}}.
+package besonjson
+
+import (
+    "bytes"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "math"
+    "math/big"
+    "sort"
+    "strconv"
+    "strings"
+
+    "beson/types"
+)
+
+type Mode int
+
+const (
+    Plain Mode = iota
+    Extended
+)
+
+var ErrUnsupportedValue = errors.New("besonjson: value has no JSON representation")
+var ErrInvalidTag = errors.New("besonjson: malformed extended JSON tag")
+
+// ToJSON returns the JSON encoding of value.
+func ToJSON(value types.RootType, mode Mode) ([]byte, error) {
+    var buf bytes.Buffer
+    if err := writeValue(&buf, value, mode); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// FromJSON parses JSON into a beson value. In Plain mode integral numbers
+// become INT64 (UINT64 beyond its range) and other numbers FLOAT64.
+func FromJSON(data []byte, mode Mode) (types.RootType, error) {
+    dec := json.NewDecoder(bytes.NewReader(data))
+    dec.UseNumber()
+
+    var v interface{}
+    if err := dec.Decode(&v); err != nil {
+        return nil, err
+    }
+    if _, err := dec.Token(); err == nil {
+        return nil, errors.New("besonjson: unexpected data after top-level value")
+    }
+    return readValue(v, mode)
+}
+
+
+/* beson to JSON */
+
+func writeValue(buf *bytes.Buffer, value types.RootType, mode Mode) error {
+    extended := mode == Extended
+
+    switch v := value.(type) {
+    case nil:
+        buf.WriteString("null")
+    case *types.Bool:
+        buf.WriteString(strconv.FormatBool(v.Get()))
+    case *types.Int8:
+        writeInteger(buf, "$int8", strconv.FormatInt(int64(v.Get()), 10), false, extended)
+    case *types.Int16:
+        writeInteger(buf, "$int16", strconv.FormatInt(int64(v.Get()), 10), false, extended)
+    case *types.Int32:
+        writeInteger(buf, "$int32", strconv.FormatInt(int64(v.Get()), 10), false, extended)
+    case *types.Int64:
+        writeInteger(buf, "$int64", strconv.FormatInt(v.Get(), 10), true, extended)
+    case *types.Int128:
+        writeInteger(buf, "$int128", bigFromBytes(v.ToBytes(), true).String(), true, extended)
+    case *types.Int256:
+        writeInteger(buf, "$int256", bigFromBytes(v.ToBytes(), true).String(), true, extended)
+    case *types.UInt8:
+        writeInteger(buf, "$uint8", strconv.FormatUint(uint64(v.Get()), 10), false, extended)
+    case *types.UInt16:
+        writeInteger(buf, "$uint16", strconv.FormatUint(uint64(v.Get()), 10), false, extended)
+    case *types.UInt32:
+        writeInteger(buf, "$uint32", strconv.FormatUint(uint64(v.Get()), 10), false, extended)
+    case *types.UInt64:
+        writeInteger(buf, "$uint64", strconv.FormatUint(v.Get(), 10), true, extended)
+    case *types.UInt128:
+        writeInteger(buf, "$uint128", bigFromBytes(v.ToBytes(), false).String(), true, extended)
+    case *types.UInt256:
+        writeInteger(buf, "$uint256", bigFromBytes(v.ToBytes(), false).String(), true, extended)
+    case *types.Float32:
+        f := float64(v.Get())
+        if !extended {
+            return writeFloat(buf, f, 32)
+        }
+        buf.WriteString(`{"$float32":`)
+        writeTaggedFloat(buf, f, 32)
+        buf.WriteString("}")
+    case *types.Float64:
+        f := v.Get()
+        if !extended {
+            return writeFloat(buf, f, 64)
+        }
+        if math.IsNaN(f) || math.IsInf(f, 0) {
+            buf.WriteString(`{"$float64":`)
+            writeTaggedFloat(buf, f, 64)
+            buf.WriteString("}")
+        } else {
+            writeFloat(buf, f, 64)
+        }
+    case *types.String:
+        writeString(buf, v.Get())
+    case *types.Binary:
+        writeTagged(buf, "$binary", "0x" + hex.EncodeToString(v.ToBytes()), extended)
+    case *types.SpecialBuffer:
+        data := "0x" + hex.EncodeToString(v.ToBytes())
+        if !extended {
+            writeString(buf, data)
+            break
+        }
+        buf.WriteString(`{"$special":{"subtype":` + strconv.Itoa(int(v.Subtype())) + `,"data":`)
+        writeString(buf, data)
+        buf.WriteString("}}")
+    case *types.Date:
+        if extended {
+            buf.WriteString(`{"$date":` + strconv.FormatInt(v.Millis(), 10) + "}")
+        } else {
+            writeString(buf, v.Get().Format("2006-01-02T15:04:05.000Z07:00"))
+        }
+    case *types.ObjectId:
+        writeTagged(buf, "$objectid", v.Hex(), extended)
+    case *types.ArrayBuffer:
+        writeTagged(buf, "$arraybuffer", "0x" + hex.EncodeToString(v.Get()), extended)
+    case *types.DataView:
+        writeTagged(buf, "$dataview", "0x" + hex.EncodeToString(v.Get()), extended)
+    case *types.UInt8Array, *types.Int8Array, *types.UInt16Array, *types.Int16Array,
+        *types.UInt32Array, *types.Int32Array, *types.Float32Array, *types.Float64Array:
+        return writeTypedArray(buf, v, extended)
+    case *types.Slice:
+        buf.WriteString("[")
+        for i, element := range v.Get() {
+            if i > 0 {
+                buf.WriteString(",")
+            }
+            if err := writeValue(buf, element, mode); err != nil {
+                return err
+            }
+        }
+        buf.WriteString("]")
+    case *types.Map:
+        return writeObject(buf, sortedKeys(v.Get()), v.Get(), mode)
+    case *types.OrderedMap:
+        return writeObject(buf, v.Keys(), v.ToMap(), mode)
+    default:
+        return ErrUnsupportedValue
+    }
+    return nil
+}
+
+func writeObject(buf *bytes.Buffer, keys []string, m map[string]types.RootType, mode Mode) error {
+    if mode == Extended && len(keys) == 1 {
+        if _, ok := tagReaders[keys[0]]; ok {
+            buf.WriteString(`{"$map":`)
+            if err := writeEntries(buf, keys, m, mode); err != nil {
+                return err
+            }
+            buf.WriteString("}")
+            return nil
+        }
+    }
+    return writeEntries(buf, keys, m, mode)
+}
+
+func writeEntries(buf *bytes.Buffer, keys []string, m map[string]types.RootType, mode Mode) error {
+    buf.WriteString("{")
+    for i, key := range keys {
+        if i > 0 {
+            buf.WriteString(",")
+        }
+        writeString(buf, key)
+        buf.WriteString(":")
+        if err := writeValue(buf, m[key], mode); err != nil {
+            return err
+        }
+    }
+    buf.WriteString("}")
+    return nil
+}
+
+// writeInteger writes a decimal integer, wrapped in its tag in extended
+// mode. Wide integers are quoted there so JavaScript keeps every digit.
+func writeInteger(buf *bytes.Buffer, tag string, digits string, wide bool, extended bool) {
+    if !extended {
+        buf.WriteString(digits)
+        return
+    }
+    if wide {
+        digits = strconv.Quote(digits)
+    }
+    buf.WriteString(`{"` + tag + `":` + digits + "}")
+}
+
+func writeTagged(buf *bytes.Buffer, tag string, str string, extended bool) {
+    if !extended {
+        writeString(buf, str)
+        return
+    }
+    buf.WriteString(`{"` + tag + `":`)
+    writeString(buf, str)
+    buf.WriteString("}")
+}
+
+func writeFloat(buf *bytes.Buffer, f float64, bitSize int) error {
+    if math.IsNaN(f) || math.IsInf(f, 0) {
+        return ErrUnsupportedValue
+    }
+    buf.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+    return nil
+}
+
+// writeTaggedFloat writes the payload of a float tag, non-finite values
+// as the strings "NaN", "Infinity" and "-Infinity".
+func writeTaggedFloat(buf *bytes.Buffer, f float64, bitSize int) {
+    buf.WriteString(formatTaggedFloat(f, bitSize))
+}
+
+func formatTaggedFloat(f float64, bitSize int) string {
+    switch {
+    case math.IsNaN(f):
+        return `"NaN"`
+    case math.IsInf(f, 1):
+        return `"Infinity"`
+    case math.IsInf(f, -1):
+        return `"-Infinity"`
+    }
+    return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// formatArrayFloat formats an element of a float typed array. Non-finite
+// values only have a representation in extended mode, as in float tags.
+func formatArrayFloat(f float64, bitSize int, extended bool) (string, error) {
+    if !extended && (math.IsNaN(f) || math.IsInf(f, 0)) {
+        return "", ErrUnsupportedValue
+    }
+    return formatTaggedFloat(f, bitSize), nil
+}
+
+func writeTypedArray(buf *bytes.Buffer, value types.RootType, extended bool) error {
+    var tag string
+    var numbers []string
+    switch v := value.(type) {
+    case *types.UInt8Array:
+        tag = "$uint8array"
+        for _, n := range v.Get() {
+            numbers = append(numbers, strconv.FormatUint(uint64(n), 10))
+        }
+    case *types.Int8Array:
+        tag = "$int8array"
+        for _, n := range v.Get() {
+            numbers = append(numbers, strconv.FormatInt(int64(n), 10))
+        }
+    case *types.UInt16Array:
+        tag = "$uint16array"
+        for _, n := range v.Get() {
+            numbers = append(numbers, strconv.FormatUint(uint64(n), 10))
+        }
+    case *types.Int16Array:
+        tag = "$int16array"
+        for _, n := range v.Get() {
+            numbers = append(numbers, strconv.FormatInt(int64(n), 10))
+        }
+    case *types.UInt32Array:
+        tag = "$uint32array"
+        for _, n := range v.Get() {
+            numbers = append(numbers, strconv.FormatUint(uint64(n), 10))
+        }
+    case *types.Int32Array:
+        tag = "$int32array"
+        for _, n := range v.Get() {
+            numbers = append(numbers, strconv.FormatInt(int64(n), 10))
+        }
+    case *types.Float32Array:
+        tag = "$float32array"
+        for _, n := range v.Get() {
+            number, err := formatArrayFloat(float64(n), 32, extended)
+            if err != nil {
+                return err
+            }
+            numbers = append(numbers, number)
+        }
+    case *types.Float64Array:
+        tag = "$float64array"
+        for _, n := range v.Get() {
+            number, err := formatArrayFloat(n, 64, extended)
+            if err != nil {
+                return err
+            }
+            numbers = append(numbers, number)
+        }
+    }
+
+    array := "[" + strings.Join(numbers, ",") + "]"
+    if extended {
+        buf.WriteString(`{"` + tag + `":` + array + "}")
+    } else {
+        buf.WriteString(array)
+    }
+    return nil
+}
+
+func writeString(buf *bytes.Buffer, str string) {
+    b, _ := json.Marshal(str)
+    buf.Write(b)
+}
+
+func sortedKeys(m map[string]types.RootType) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+
+/* JSON to beson */
+
+func readValue(v interface{}, mode Mode) (types.RootType, error) {
+    switch value := v.(type) {
+    case nil:
+        return nil, nil
+    case bool:
+        return types.NewBool(value), nil
+    case string:
+        return types.NewString(value), nil
+    case json.Number:
+        if mode == Extended {
+            f, err := value.Float64()
+            if err != nil {
+                return nil, err
+            }
+            return types.NewFloat64(f), nil
+        }
+        return readPlainNumber(value)
+    case []interface{}:
+        slice := make([]types.RootType, len(value))
+        for i, element := range value {
+            item, err := readValue(element, mode)
+            if err != nil {
+                return nil, err
+            }
+            slice[i] = item
+        }
+        return types.NewSlice(slice), nil
+    case map[string]interface{}:
+        if mode == Extended && len(value) == 1 {
+            for key, payload := range value {
+                if reader, ok := tagReaders[key]; ok {
+                    return reader(payload)
+                }
+            }
+        }
+        return readEntries(value, mode)
+    }
+    return nil, ErrUnsupportedValue
+}
+
+func readEntries(object map[string]interface{}, mode Mode) (types.RootType, error) {
+    m := make(map[string]types.RootType, len(object))
+    for key, element := range object {
+        item, err := readValue(element, mode)
+        if err != nil {
+            return nil, err
+        }
+        m[key] = item
+    }
+    return types.NewMap(m), nil
+}
+
+// readEscapedMap parses the payload of the $map tag, a map whose single
+// key would otherwise be read as a tag.
+func readEscapedMap(payload interface{}) (types.RootType, error) {
+    object, ok := payload.(map[string]interface{})
+    if !ok {
+        return nil, ErrInvalidTag
+    }
+    return readEntries(object, Extended)
+}
+
+func readPlainNumber(n json.Number) (types.RootType, error) {
+    if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+        return types.NewInt64(i), nil
+    }
+    if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+        return types.NewUInt64(u), nil
+    }
+    f, err := n.Float64()
+    if err != nil {
+        return nil, err
+    }
+    return types.NewFloat64(f), nil
+}
+
+var tagReaders map[string]func(interface{}) (types.RootType, error)
+
+func init() {
+    tagReaders = map[string]func(interface{}) (types.RootType, error) {
+        "$int8":    integerReader(true, 8),
+        "$int16":   integerReader(true, 16),
+        "$int32":   integerReader(true, 32),
+        "$int64":   integerReader(true, 64),
+        "$int128":  integerReader(true, 128),
+        "$int256":  integerReader(true, 256),
+        "$uint8":   integerReader(false, 8),
+        "$uint16":  integerReader(false, 16),
+        "$uint32":  integerReader(false, 32),
+        "$uint64":  integerReader(false, 64),
+        "$uint128": integerReader(false, 128),
+        "$uint256": integerReader(false, 256),
+        "$float32": floatReader(32),
+        "$float64": floatReader(64),
+        "$binary":  hexReader(func(bs []byte) types.RootType {
+            return types.NewBinary(0).(*types.Binary).FromBytes(bs)
+        }),
+        "$arraybuffer": hexReader(func(bs []byte) types.RootType { return types.NewArrayBuffer(bs) }),
+        "$dataview":    hexReader(func(bs []byte) types.RootType { return types.NewDataView(bs) }),
+        "$date":        readDate,
+        "$objectid":    readObjectId,
+        "$special":     readSpecialBuffer,
+        "$map":         readEscapedMap,
+        "$uint8array":  typedArrayReader(integerReader(false, 8), false, func(items []types.RootType) types.RootType {
+            array := make([]uint8, len(items))
+            for i, item := range items {
+                array[i] = item.(*types.UInt8).Get()
+            }
+            return types.NewUInt8Array(array)
+        }),
+        "$int8array":   typedArrayReader(integerReader(true, 8), false, func(items []types.RootType) types.RootType {
+            array := make([]int8, len(items))
+            for i, item := range items {
+                array[i] = item.(*types.Int8).Get()
+            }
+            return types.NewInt8Array(array)
+        }),
+        "$uint16array": typedArrayReader(integerReader(false, 16), false, func(items []types.RootType) types.RootType {
+            array := make([]uint16, len(items))
+            for i, item := range items {
+                array[i] = item.(*types.UInt16).Get()
+            }
+            return types.NewUInt16Array(array)
+        }),
+        "$int16array":  typedArrayReader(integerReader(true, 16), false, func(items []types.RootType) types.RootType {
+            array := make([]int16, len(items))
+            for i, item := range items {
+                array[i] = item.(*types.Int16).Get()
+            }
+            return types.NewInt16Array(array)
+        }),
+        "$uint32array": typedArrayReader(integerReader(false, 32), false, func(items []types.RootType) types.RootType {
+            array := make([]uint32, len(items))
+            for i, item := range items {
+                array[i] = item.(*types.UInt32).Get()
+            }
+            return types.NewUInt32Array(array)
+        }),
+        "$int32array":  typedArrayReader(integerReader(true, 32), false, func(items []types.RootType) types.RootType {
+            array := make([]int32, len(items))
+            for i, item := range items {
+                array[i] = item.(*types.Int32).Get()
+            }
+            return types.NewInt32Array(array)
+        }),
+        "$float32array": typedArrayReader(floatReader(32), true, func(items []types.RootType) types.RootType {
+            array := make([]float32, len(items))
+            for i, item := range items {
+                array[i] = item.(*types.Float32).Get()
+            }
+            return types.NewFloat32Array(array)
+        }),
+        "$float64array": typedArrayReader(floatReader(64), true, func(items []types.RootType) types.RootType {
+            array := make([]float64, len(items))
+            for i, item := range items {
+                array[i] = item.(*types.Float64).Get()
+            }
+            return types.NewFloat64Array(array)
+        }),
+    }
+}
+
+// integerReader parses the payload of an integer tag, a JSON number or a
+// decimal string, and checks it fits in bits.
+func integerReader(signed bool, bits uint) func(interface{}) (types.RootType, error) {
+    return func(payload interface{}) (types.RootType, error) {
+        var digits string
+        switch p := payload.(type) {
+        case json.Number:
+            digits = p.String()
+        case string:
+            digits = p
+        default:
+            return nil, ErrInvalidTag
+        }
+
+        n, ok := new(big.Int).SetString(digits, 10)
+        if !ok || !fits(n, signed, bits) {
+            return nil, ErrInvalidTag
+        }
+        return integerFromBig(n, signed, bits), nil
+    }
+}
+
+func fits(n *big.Int, signed bool, bits uint) bool {
+    if !signed {
+        return n.Sign() >= 0 && uint(n.BitLen()) <= bits
+    }
+    limit := new(big.Int).Lsh(big.NewInt(1), bits - 1)
+    return n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0
+}
+
+func integerFromBig(n *big.Int, signed bool, bits uint) types.RootType {
+    switch {
+    case signed && bits == 8:
+        return types.NewInt8(int8(n.Int64()))
+    case signed && bits == 16:
+        return types.NewInt16(int16(n.Int64()))
+    case signed && bits == 32:
+        return types.NewInt32(int32(n.Int64()))
+    case signed && bits == 64:
+        return types.NewInt64(n.Int64())
+    case !signed && bits == 8:
+        return types.NewUInt8(uint8(n.Uint64()))
+    case !signed && bits == 16:
+        return types.NewUInt16(uint16(n.Uint64()))
+    case !signed && bits == 32:
+        return types.NewUInt32(uint32(n.Uint64()))
+    case !signed && bits == 64:
+        return types.NewUInt64(n.Uint64())
+    }
+
+    bs := bytesFromBig(n, int(bits / 8))
+    if bits == 256 {
+        if signed {
+            return types.Int256FromBytes(bs)
+        }
+        return types.UInt256FromBytes(bs)
+    }
+
+    low, high := leUint64(bs[:8]), leUint64(bs[8:16])
+    if signed {
+        value := &types.Int128{}
+        value.SetLow(low)
+        value.SetHigh(high)
+        return value
+    }
+    value := &types.UInt128{}
+    value.SetLow(low)
+    value.SetHigh(high)
+    return value
+}
+
+func floatReader(bits int) func(interface{}) (types.RootType, error) {
+    return func(payload interface{}) (types.RootType, error) {
+        var f float64
+        switch p := payload.(type) {
+        case json.Number:
+            parsed, err := strconv.ParseFloat(p.String(), bits)
+            if err != nil {
+                return nil, ErrInvalidTag
+            }
+            f = parsed
+        case string:
+            switch p {
+            case "NaN":
+                f = math.NaN()
+            case "Infinity":
+                f = math.Inf(1)
+            case "-Infinity":
+                f = math.Inf(-1)
+            default:
+                return nil, ErrInvalidTag
+            }
+        default:
+            return nil, ErrInvalidTag
+        }
+
+        if bits == 32 {
+            return types.NewFloat32(float32(f)), nil
+        }
+        return types.NewFloat64(f), nil
+    }
+}
+
+func hexReader(build func([]byte) types.RootType) func(interface{}) (types.RootType, error) {
+    return func(payload interface{}) (types.RootType, error) {
+        str, ok := payload.(string)
+        if !ok {
+            return nil, ErrInvalidTag
+        }
+        bs, err := hex.DecodeString(strings.TrimPrefix(str, "0x"))
+        if err != nil {
+            return nil, ErrInvalidTag
+        }
+        return build(bs), nil
+    }
+}
+
+func readDate(payload interface{}) (types.RootType, error) {
+    n, ok := payload.(json.Number)
+    if !ok {
+        return nil, ErrInvalidTag
+    }
+    ms, err := n.Int64()
+    if err != nil {
+        return nil, ErrInvalidTag
+    }
+    return types.NewDateFromMillis(ms), nil
+}
+
+func readObjectId(payload interface{}) (types.RootType, error) {
+    str, ok := payload.(string)
+    if !ok {
+        return nil, ErrInvalidTag
+    }
+    id, err := types.ObjectIdFromHex(str)
+    if err != nil {
+        return nil, ErrInvalidTag
+    }
+    return id, nil
+}
+
+// readSpecialBuffer parses {"subtype":n,"data":"0x..."}.
+func readSpecialBuffer(payload interface{}) (types.RootType, error) {
+    object, ok := payload.(map[string]interface{})
+    if !ok || len(object) != 2 {
+        return nil, ErrInvalidTag
+    }
+    subtype, err := integerReader(false, 8)(object["subtype"])
+    if err != nil {
+        return nil, ErrInvalidTag
+    }
+    data, err := hexReader(func(bs []byte) types.RootType {
+        return types.NewSpecialBuffer(subtype.(*types.UInt8).Get(), bs)
+    })(object["data"])
+    if err != nil {
+        return nil, ErrInvalidTag
+    }
+    return data, nil
+}
+
+// typedArrayReader parses the payload of a typed array tag, a list of
+// numbers each checked by element, and hands the results to build. With
+// floats set it also accepts the strings floatReader takes for non-finite
+// values.
+func typedArrayReader(element func(interface{}) (types.RootType, error), floats bool, build func([]types.RootType) types.RootType) func(interface{}) (types.RootType, error) {
+    return func(payload interface{}) (types.RootType, error) {
+        list, ok := payload.([]interface{})
+        if !ok {
+            return nil, ErrInvalidTag
+        }
+        items := make([]types.RootType, len(list))
+        for i, n := range list {
+            _, number := n.(json.Number)
+            _, text := n.(string)
+            if !number && !(text && floats) {
+                return nil, ErrInvalidTag
+            }
+            item, err := element(n)
+            if err != nil {
+                return nil, err
+            }
+            items[i] = item
+        }
+        return build(items), nil
+    }
+}
+
+func bigFromBytes(bs []byte, signed bool) *big.Int {
+    be := make([]byte, len(bs))
+    for i, b := range bs {
+        be[len(bs) - 1 - i] = b
+    }
+    n := new(big.Int).SetBytes(be)
+    if signed && len(bs) > 0 && bs[len(bs) - 1] & 0x80 != 0 {
+        n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(bs) * 8)))
+    }
+    return n
+}
+
+func bytesFromBig(n *big.Int, size int) []byte {
+    m := new(big.Int).Set(n)
+    if m.Sign() < 0 {
+        m.Add(m, new(big.Int).Lsh(big.NewInt(1), uint(size * 8)))
+    }
+    be := m.FillBytes(make([]byte, size))
+    bs := make([]byte, size)
+    for i, b := range be {
+        bs[size - 1 - i] = b
+    }
+    return bs
+}
+
+func leUint64(bs []byte) uint64 {
+    var n uint64
+    for i := len(bs) - 1; i >= 0; i-- {
+        n = n << 8 | uint64(bs[i])
+    }
+    return n
+}
@@ -0,0 +1,124 @@
+package besonjson
+
+import (
+    "bytes"
+    "math"
+    "testing"
+
+    "beson"
+    "beson/types"
+)
+
+var extendedData = map[string]types.RootType {
+    "INT8":     types.NewInt8(-3),
+    "INT64":    types.NewInt64(-9223372036854775808),
+    "INT128":   types.NewInt128("-170141183460469231731687303715884105728", 10).(*types.Int128),
+    "UINT128":  types.NewUInt128("340282366920938463463374607431768211455", 10).(*types.UInt128),
+    "FLOAT32":  types.NewFloat32(0.456),
+    "FLOAT64":  types.NewFloat64(0.456),
+    "BINARY":   types.NewBinary(0).(*types.Binary).FromBytes([]byte{ 0x02, 0x56 }),
+    "DATE":     types.NewDateFromMillis(1554249600123),
+    "OBJECTID": types.NewObjectId(),
+    "TYPED":    types.NewInt16Array([]int16{ -1, 2 }),
+    "SPECIAL":  types.NewSpecialBuffer(types.SPECIAL_SUBTYPE_UUID, []byte{ 0x02, 0x56 }),
+    "MAP":      types.NewMap(map[string]types.RootType {
+        "apple":    types.NewUInt8(2),
+        "banana":   types.NewSlice([]types.RootType { nil, types.NewBool(false), types.NewString("x") }),
+    }),
+}
+
+func TestExtendedRoundTrip(t *testing.T) {
+    for name, value := range extendedData {
+        t.Run(name, testExtendedRoundTripFunc(value))
+    }
+}
+
+func testExtendedRoundTripFunc(value types.RootType) func(*testing.T) {
+    return func(t *testing.T) {
+        data, err := ToJSON(value, Extended)
+        if err != nil {
+            t.Fatalf("ToJSON failed: %v", err)
+        }
+        actual, err := FromJSON(data, Extended)
+        if err == nil && bytes.Equal(beson.Serialize(actual), beson.Serialize(value)) {
+            t.Log("Extended round trip test passed.")
+        } else {
+            t.Errorf("Extended round trip test failed: %s %v", data, err)
+        }
+    }
+}
+
+func TestExtendedTagKeys(t *testing.T) {
+    values := map[string]types.RootType {
+        "DATE":     types.NewMap(map[string]types.RootType { "$date": types.NewString("x") }),
+        "INT8":     types.NewMap(map[string]types.RootType { "$int8": types.NewInt8(1) }),
+        "MAP":      types.NewMap(map[string]types.RootType { "$map": types.NewMap(map[string]types.RootType { "$int8": nil }) }),
+        "TWO_KEYS": types.NewMap(map[string]types.RootType { "$int8": types.NewInt8(1), "a": nil }),
+    }
+    for name, value := range values {
+        t.Run(name, testExtendedRoundTripFunc(value))
+    }
+    t.Run("ESCAPED", testToJSONFunc(values["INT8"], Extended, `{"$map":{"$int8":{"$int8":1}}}`))
+}
+
+func TestExtendedNonFiniteArrays(t *testing.T) {
+    values := map[string]types.RootType {
+        "FLOAT32":  types.NewFloat32Array([]float32{ float32(math.NaN()), float32(math.Inf(1)), 1.5 }),
+        "FLOAT64":  types.NewFloat64Array([]float64{ math.Inf(-1), math.NaN(), 0 }),
+    }
+    for name, value := range values {
+        t.Run(name, testExtendedRoundTripFunc(value))
+    }
+    t.Run("TAGGED", testToJSONFunc(values["FLOAT64"], Extended, `{"$float64array":["-Infinity","NaN",0]}`))
+    if _, err := ToJSON(values["FLOAT64"], Plain); err != ErrUnsupportedValue {
+        t.Errorf("Plain mode should reject non-finite array elements: %v", err)
+    }
+    if _, err := FromJSON([]byte(`{"$int8array":["1"]}`), Extended); err != ErrInvalidTag {
+        t.Errorf("Integer arrays should only hold numbers: %v", err)
+    }
+}
+
+func TestToJSON(t *testing.T) {
+    t.Run("PLAIN", testToJSONFunc(extendedData["MAP"], Plain, `{"apple":2,"banana":[null,false,"x"]}`))
+    t.Run("EXTENDED", testToJSONFunc(extendedData["MAP"], Extended, `{"apple":{"$uint8":2},"banana":[null,false,"x"]}`))
+    t.Run("UINT128", testToJSONFunc(extendedData["UINT128"], Extended, `{"$uint128":"340282366920938463463374607431768211455"}`))
+    t.Run("BINARY", testToJSONFunc(extendedData["BINARY"], Extended, `{"$binary":"0x0256"}`))
+    t.Run("SPECIAL", testToJSONFunc(extendedData["SPECIAL"], Extended, `{"$special":{"subtype":1,"data":"0x0256"}}`))
+    t.Run("FLOAT32", testToJSONFunc(extendedData["FLOAT32"], Extended, `{"$float32":0.456}`))
+    t.Run("DATE", testToJSONFunc(extendedData["DATE"], Plain, `"2019-04-03T00:00:00.123Z"`))
+}
+
+func testToJSONFunc(value types.RootType, mode Mode, expect string) func(*testing.T) {
+    return func(t *testing.T) {
+        actual, err := ToJSON(value, mode)
+        if err == nil && string(actual) == expect {
+            t.Log("ToJSON test passed.")
+        } else {
+            t.Errorf("ToJSON test failed: %s %v", actual, err)
+        }
+    }
+}
+
+func TestFromJSON(t *testing.T) {
+    value, err := FromJSON([]byte(`[1, 18446744073709551615, 1.5]`), Plain)
+    if err != nil {
+        t.Fatalf("FromJSON failed: %v", err)
+    }
+    slice := value.(*types.Slice).Get()
+    if _, ok := slice[0].(*types.Int64); !ok {
+        t.Error("Integral numbers should become INT64.")
+    }
+    if _, ok := slice[1].(*types.UInt64); !ok {
+        t.Error("Integers beyond INT64 should become UINT64.")
+    }
+    if _, ok := slice[2].(*types.Float64); !ok {
+        t.Error("Fractional numbers should become FLOAT64.")
+    }
+
+    if _, err := FromJSON([]byte(`{"$int8":300}`), Extended); err != ErrInvalidTag {
+        t.Errorf("Out of range tag should be rejected: %v", err)
+    }
+    if _, err := FromJSON([]byte(`1 2`), Plain); err == nil {
+        t.Error("Trailing data should be rejected.")
+    }
+}
@@ -0,0 +1,163 @@
+package beson
+
+import (
+    "bytes"
+    "encoding/binary"
+
+    "beson/types"
+)
+
+// Options tunes Serialize and Deserialize.
+type Options struct {
+    // Canonical selects the canonical encoding: map keys in byte order,
+    // integers in their smallest width and no duplicate map keys.
+    Canonical bool
+
+    // OrderedMaps makes the decoder produce *types.OrderedMap values that
+    // keep map entries in wire order instead of *types.Map.
+    OrderedMaps bool
+
+    // Limits bounds what the decoder accepts, nil selects
+    // DefaultDecodeOptions.
+    Limits *DecodeOptions
+
+    // Registry resolves extension types, nil selects DefaultRegistry.
+    Registry *Registry
+}
+
+// IsCanonical reports whether data holds exactly one value in canonical
+// encoding, i.e. whether SerializeWithOptions with Canonical set would
+// produce the very same bytes.
+func IsCanonical(data []byte) bool {
+    end, value, err := DeserializeWithOptions(data, 0, Options { Canonical: true })
+    if err != nil || end != uint32(len(data)) {
+        return false
+    }
+    return bytes.Equal(SerializeWithOptions(value, Options { Canonical: true }), data)
+}
+
+// canonicalize returns a copy of the tree rooted at data with every
+// integer narrowed to its smallest width.
+func canonicalize(data interface{}) interface{} {
+    switch value := marshaled(data).(type) {
+    case *types.Int8, *types.Int16, *types.Int32, *types.Int64, *types.Int128, *types.Int256:
+        return narrowInteger(integerBytes(value), true)
+    case *types.UInt8, *types.UInt16, *types.UInt32, *types.UInt64, *types.UInt128, *types.UInt256:
+        return narrowInteger(integerBytes(value), false)
+    case *types.Slice:
+        slice := make([]types.RootType, len(value.Get()))
+        for i, element := range value.Get() {
+            slice[i] = canonicalize(element)
+        }
+        return types.NewSlice(slice)
+    case *types.Map, *types.OrderedMap:
+        keys, values := mapEntries(value)
+        m := make(map[string]types.RootType, len(keys))
+        for i, key := range keys {
+            m[key] = canonicalize(values[i])
+        }
+        return types.NewMap(m)
+    default:
+        return value
+    }
+}
+
+// integerBytes returns the little endian two's complement representation
+// of an integer wrapper.
+func integerBytes(value types.RootType) []byte {
+    bs := make([]byte, 8)
+    switch v := value.(type) {
+    case *types.Int8:
+        binary.LittleEndian.PutUint64(bs, uint64(int64(v.Get())))
+    case *types.Int16:
+        binary.LittleEndian.PutUint64(bs, uint64(int64(v.Get())))
+    case *types.Int32:
+        binary.LittleEndian.PutUint64(bs, uint64(int64(v.Get())))
+    case *types.Int64:
+        binary.LittleEndian.PutUint64(bs, uint64(v.Get()))
+    case *types.UInt8:
+        binary.LittleEndian.PutUint64(bs, uint64(v.Get()))
+    case *types.UInt16:
+        binary.LittleEndian.PutUint64(bs, uint64(v.Get()))
+    case *types.UInt32:
+        binary.LittleEndian.PutUint64(bs, uint64(v.Get()))
+    case *types.UInt64:
+        binary.LittleEndian.PutUint64(bs, v.Get())
+    case *types.Int128:
+        bs = v.ToBytes()
+    case *types.UInt128:
+        bs = v.ToBytes()
+    case *types.Int256:
+        bs = v.ToBytes()
+    case *types.UInt256:
+        bs = v.ToBytes()
+    }
+    return bs
+}
+
+// narrowInteger picks the smallest width whose sign (or zero) extension
+// gives back bs and builds the matching wrapper.
+func narrowInteger(bs []byte, signed bool) types.RootType {
+    width := len(bs)
+    for _, candidate := range []int{ 1, 2, 4, 8, 16 } {
+        if candidate < len(bs) && isExtensionOf(bs, candidate, signed) {
+            width = candidate
+            break
+        }
+    }
+
+    b := bs[:width]
+    switch width {
+    case 1:
+        if signed {
+            return types.NewInt8(int8(b[0]))
+        }
+        return types.NewUInt8(b[0])
+    case 2:
+        if signed {
+            return types.NewInt16(int16(binary.LittleEndian.Uint16(b)))
+        }
+        return types.NewUInt16(binary.LittleEndian.Uint16(b))
+    case 4:
+        if signed {
+            return types.NewInt32(int32(binary.LittleEndian.Uint32(b)))
+        }
+        return types.NewUInt32(binary.LittleEndian.Uint32(b))
+    case 8:
+        if signed {
+            return types.NewInt64(int64(binary.LittleEndian.Uint64(b)))
+        }
+        return types.NewUInt64(binary.LittleEndian.Uint64(b))
+    case 16:
+        if signed {
+            value := &types.Int128{}
+            value.SetLow(binary.LittleEndian.Uint64(b[:8]))
+            value.SetHigh(binary.LittleEndian.Uint64(b[8:]))
+            return value
+        }
+        value := &types.UInt128{}
+        value.SetLow(binary.LittleEndian.Uint64(b[:8]))
+        value.SetHigh(binary.LittleEndian.Uint64(b[8:]))
+        return value
+    }
+
+    if signed {
+        return types.Int256FromBytes(b)
+    }
+    return types.UInt256FromBytes(b)
+}
+
+// isExtensionOf reports whether the bytes of bs beyond width only repeat
+// the sign (or zero) extension of its first width bytes.
+func isExtensionOf(bs []byte, width int, signed bool) bool {
+    var fill byte = 0
+    if signed && bs[width - 1] & 0x80 != 0 {
+        fill = 0xff
+    }
+    for _, b := range bs[width:] {
+        if b != fill {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,98 @@
+package beson
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+
+    "beson/types"
+)
+
+func TestSerializeCanonical(t *testing.T) {
+    canonical := Options { Canonical: true }
+    t.Run("INT64", testSerializeCanonicalFunc(types.NewInt64(-3), serializedData["INT8"]))
+    t.Run("INT32", testSerializeCanonicalFunc(types.NewInt32(-200), []byte{ 2, 5, 56, 255 }))
+    t.Run("UINT64", testSerializeCanonicalFunc(types.NewUInt64(2), serializedData["UINT8"]))
+    t.Run("UINT128", testSerializeCanonicalFunc(originData["UINT128"], serializedData["UINT8"]))
+    t.Run("INT256", testSerializeCanonicalFunc(types.NewInt256("-3", 10), serializedData["INT8"]))
+    t.Run("UINT256", testSerializeCanonicalFunc(types.NewUInt256("4294967296", 10), []byte{ 3, 1, 0, 0, 0, 0, 1, 0, 0, 0 }))
+    t.Run("MAP", testSerializeCanonicalFunc(types.NewMap(map[string]types.RootType {
+        "banana":   types.NewBool(false),
+        "apple":    types.NewUInt32(2),
+    }), serializedData["MAP"]))
+
+    // Repeated runs must not depend on map iteration order.
+    m := types.NewMap(map[string]types.RootType{ "a": nil, "b": nil, "c": nil, "d": nil, "e": nil })
+    first := SerializeWithOptions(m, canonical)
+    for i := 0; i < 20; i++ {
+        if !reflect.DeepEqual(SerializeWithOptions(m, canonical), first) {
+            t.Fatal("Canonical encoding is not deterministic.")
+        }
+    }
+}
+
+func testSerializeCanonicalFunc(data interface{}, expect []byte) func(*testing.T) {
+    return func(t *testing.T) {
+        actual := SerializeWithOptions(data, Options { Canonical: true })
+        if reflect.DeepEqual(actual, expect) && IsCanonical(actual) {
+            t.Log("SerializeWithOptions test passed.")
+        } else {
+            t.Errorf("SerializeWithOptions test failed: %v", actual)
+        }
+    }
+}
+
+func TestIsCanonical(t *testing.T) {
+    t.Run("INT32", testIsCanonicalFunc(serializedData["INT32"], false))
+    t.Run("STRING", testIsCanonicalFunc(serializedData["STRING"], true))
+    t.Run("TRAILING", testIsCanonicalFunc(append(append([]byte{}, serializedData["TRUE"]...), 0, 0), false))
+    t.Run("UNSORTED", testIsCanonicalFunc([]byte{ 9, 0, 10, 0, 0, 0, 0, 0, 1, 0, 98, 0, 0, 1, 0, 97 }, false))
+    t.Run("DUPLICATE", testIsCanonicalFunc([]byte{ 9, 0, 10, 0, 0, 0, 0, 0, 1, 0, 97, 0, 0, 1, 0, 97 }, false))
+    t.Run("STREAM", testIsCanonicalFunc([]byte{ 7, 0, 8, 0 }, false))
+}
+
+func testIsCanonicalFunc(data []byte, expect bool) func(*testing.T) {
+    return func(t *testing.T) {
+        if IsCanonical(data) == expect {
+            t.Log("IsCanonical test passed.")
+        } else {
+            t.Error("IsCanonical test failed.")
+        }
+    }
+}
+
+func TestDeserializeDuplicateKey(t *testing.T) {
+    data := []byte{ 9, 0, 10, 0, 0, 0, 0, 0, 1, 0, 97, 0, 0, 1, 0, 97 }
+    if _, _, err := DeserializeE(data, 0); err != nil {
+        t.Errorf("Duplicate keys should be accepted by default: %v", err)
+    }
+
+    _, _, err := DeserializeWithOptions(data, 0, Options { Canonical: true })
+    var derr *DeserializeError
+    if !errors.As(err, &derr) || derr.Err != ErrDuplicateKey || derr.Offset != 13 {
+        t.Errorf("Duplicate key should be rejected at offset 13, got %v", err)
+    }
+}
+
+func TestOrderedMap(t *testing.T) {
+    // "banana" before "apple", the reverse of the sorted MAP encoding.
+    data := []byte{ 9, 0, 20, 0, 0, 0, 1, 0, 6, 0, 98, 97, 110, 97, 110, 97, 3, 4, 5, 0, 97, 112, 112, 108, 101, 2 }
+
+    _, value, err := DeserializeWithOptions(data, 0, Options { OrderedMaps: true })
+    ordered, ok := value.(*types.OrderedMap)
+    if err != nil || !ok || !reflect.DeepEqual(ordered.Keys(), []string{ "banana", "apple" }) {
+        t.Fatalf("Deserialize into OrderedMap failed: %v", err)
+    }
+    if !reflect.DeepEqual(Serialize(ordered), data) {
+        t.Error("OrderedMap should be serialized in key order.")
+    }
+    if !reflect.DeepEqual(SerializeWithOptions(ordered, Options { Canonical: true }), serializedData["MAP"]) {
+        t.Error("Canonical OrderedMap should be serialized in byte order.")
+    }
+
+    stream := []byte{ 10, 0, 1, 0, 1, 0, 98, 1, 0, 1, 0, 97, 11, 0 }
+    _, value, err = DeserializeWithOptions(stream, 0, Options { OrderedMaps: true })
+    if err != nil || !reflect.DeepEqual(value.(*types.OrderedMap).Keys(), []string{ "b", "a" }) {
+        t.Errorf("Deserialize MAP_START into OrderedMap failed: %v", err)
+    }
+}
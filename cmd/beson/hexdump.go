@@ -0,0 +1,87 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "strings"
+
+    "beson"
+)
+
+// hexdump prints every field of the value encoded in data, one line per
+// field, with its offset, bytes and meaning. Fields read before an error
+// are printed as well.
+func hexdump(w io.Writer, data []byte) error {
+    nodes, err := beson.Explain(data)
+
+    // Delimited containers whose end marker is still to be printed. Those
+    // left unfinished by an error have no PayloadEnd and stay open.
+    var open []beson.Node
+    closeUntil := func(offset uint32) {
+        for len(open) > 0 {
+            node := open[len(open) - 1]
+            if node.PayloadEnd == 0 || node.PayloadEnd > offset {
+                return
+            }
+            open = open[:len(open) - 1]
+            printField(w, data, node.PayloadEnd - 2, 2, depth(node), "header " + endMarker(node.Kind).String())
+        }
+    }
+
+    for _, node := range nodes {
+        closeUntil(node.Offset)
+        d := depth(node)
+
+        printField(w, data, node.Offset, 2, d, "header " + node.Kind.String())
+        if node.Keyed {
+            keyLength := uint32(len(node.Key))
+            printField(w, data, node.Offset + 2, 2, d, fmt.Sprintf("key length %d", keyLength))
+            printField(w, data, node.Offset + 4, keyLength, d, fmt.Sprintf("key %q", node.Key))
+        }
+        if node.HasLength {
+            printField(w, data, node.PayloadStart - 4, 4, d, fmt.Sprintf("length %d", node.Length))
+        }
+
+        switch node.Kind {
+        case beson.KindArrayStart, beson.KindMapStart:
+            open = append(open, node)
+        case beson.KindArray, beson.KindMap:
+        default:
+            // Never read past data, whatever the node claims.
+            if node.PayloadEnd < node.PayloadStart || node.PayloadEnd > uint32(len(data)) {
+                continue
+            }
+            printField(w, data, node.PayloadStart, node.PayloadEnd - node.PayloadStart, d, "payload")
+        }
+    }
+    if err == nil {
+        closeUntil(uint32(len(data)))
+    }
+    return err
+}
+
+func depth(node beson.Node) int {
+    return strings.Count(node.Path, "/")
+}
+
+func endMarker(k beson.Kind) beson.Kind {
+    if k == beson.KindMapStart {
+        return beson.KindMapEnd
+    }
+    return beson.KindArrayEnd
+}
+
+// printField prints size bytes from pos, 16 per row, the note on the first
+// row. Empty fields are not printed.
+func printField(w io.Writer, data []byte, pos uint32, size uint32, depth int, note string) {
+    indent := strings.Repeat("  ", depth)
+    for row := uint32(0); row < size; row += 16 {
+        n := size - row
+        if n > 16 {
+            n = 16
+        }
+        hex := fmt.Sprintf("% x", data[pos + row:pos + row + n])
+        fmt.Fprintf(w, "%08x  %-48s  %s%s\n", pos + row, hex, indent, note)
+        note = ""
+    }
+}
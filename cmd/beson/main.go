@@ -0,0 +1,196 @@
+// Command beson inspects and converts beson encoded data.
+//
+// Usage:
+//
+//     beson dump [file]                 print the decoded value as a tree
+//     beson hexdump [file]              annotate every byte of the encoding
+//     beson tojson [-extended] [-indent] [file]
+//     beson fromjson [-extended] [file]
+//     beson validate [-canonical] [file]
+//
+// Input is read from file, or from stdin when it is omitted or "-".
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    "sort"
+    "strings"
+
+    "beson"
+    "beson/besonjson"
+    "beson/types"
+)
+
+const usage = `usage: beson <command> [flags] [file]
+
+commands:
+    dump        print the decoded value as a tree
+    hexdump     annotate header, length prefix and payload of every node
+    tojson      convert to JSON
+    fromjson    convert JSON to beson
+    validate    check that the input holds exactly one valid value
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+    if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+        if err == errUsage {
+            fmt.Fprint(os.Stderr, usage)
+            os.Exit(2)
+        }
+        fmt.Fprintln(os.Stderr, "beson:", strings.TrimPrefix(err.Error(), "beson: "))
+        os.Exit(1)
+    }
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+    if len(args) == 0 {
+        return errUsage
+    }
+
+    flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+    extended := flags.Bool("extended", false, "use extended JSON that keeps every beson type")
+    indent := flags.Bool("indent", false, "indent the JSON output")
+    canonical := flags.Bool("canonical", false, "also require the canonical encoding")
+    if err := flags.Parse(args[1:]); err != nil {
+        return errUsage
+    }
+    if flags.NArg() > 1 {
+        return errUsage
+    }
+
+    input, err := readInput(flags.Arg(0), stdin)
+    if err != nil {
+        return err
+    }
+
+    mode := besonjson.Plain
+    if *extended {
+        mode = besonjson.Extended
+    }
+
+    switch args[0] {
+    case "dump":
+        value, err := decode(input)
+        if err != nil {
+            return err
+        }
+        return dump(stdout, value)
+    case "hexdump":
+        return hexdump(stdout, input)
+    case "tojson":
+        value, err := decode(input)
+        if err != nil {
+            return err
+        }
+        out, err := besonjson.ToJSON(value, mode)
+        if err != nil {
+            return err
+        }
+        if *indent {
+            var buf bytes.Buffer
+            json.Indent(&buf, out, "", "    ")
+            out = buf.Bytes()
+        }
+        _, err = fmt.Fprintf(stdout, "%s\n", out)
+        return err
+    case "fromjson":
+        value, err := besonjson.FromJSON(input, mode)
+        if err != nil {
+            return err
+        }
+        _, err = stdout.Write(beson.Serialize(value))
+        return err
+    case "validate":
+        if _, err := decode(input); err != nil {
+            return err
+        }
+        if *canonical && !beson.IsCanonical(input) {
+            return errors.New("value is not canonically encoded")
+        }
+        _, err := fmt.Fprintln(stdout, "ok")
+        return err
+    }
+    return errUsage
+}
+
+func readInput(name string, stdin io.Reader) ([]byte, error) {
+    if name == "" || name == "-" {
+        return ioutil.ReadAll(stdin)
+    }
+    return ioutil.ReadFile(name)
+}
+
+// decode reads the single value held by input, keeping map entries in wire
+// order.
+func decode(input []byte) (types.RootType, error) {
+    end, value, err := beson.DeserializeWithOptions(input, 0, beson.Options { OrderedMaps: true })
+    if err != nil {
+        return nil, err
+    }
+    if end != uint32(len(input)) {
+        return nil, &beson.DeserializeError { Offset: int64(end), Err: beson.ErrTrailingData }
+    }
+    return value, nil
+}
+
+// dump prints value as an indented tree, one node per line with its type
+// name and, for leaves, its value.
+func dump(w io.Writer, value types.RootType) error {
+    return dumpNode(w, value, "", 0)
+}
+
+func dumpNode(w io.Writer, value types.RootType, label string, depth int) error {
+    prefix := strings.Repeat("    ", depth) + label
+    t := beson.TypeOf(value)
+
+    switch v := value.(type) {
+    case *types.Slice:
+        fmt.Fprintf(w, "%s%s (%d)\n", prefix, t, len(v.Get()))
+        for i, element := range v.Get() {
+            if err := dumpNode(w, element, fmt.Sprintf("[%d] ", i), depth + 1); err != nil {
+                return err
+            }
+        }
+        return nil
+    case *types.Map, *types.OrderedMap:
+        keys, m := mapEntries(v)
+        fmt.Fprintf(w, "%s%s (%d)\n", prefix, t, len(keys))
+        for _, key := range keys {
+            if err := dumpNode(w, m[key], fmt.Sprintf("%q: ", key), depth + 1); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    // Non-finite floats have no plain JSON form, fall back to the tagged one.
+    text, err := besonjson.ToJSON(value, besonjson.Plain)
+    if err != nil {
+        text, _ = besonjson.ToJSON(value, besonjson.Extended)
+    }
+    _, err = fmt.Fprintf(w, "%s%s %s\n", prefix, t, text)
+    return err
+}
+
+func mapEntries(value types.RootType) ([]string, map[string]types.RootType) {
+    if ordered, ok := value.(*types.OrderedMap); ok {
+        return ordered.Keys(), ordered.ToMap()
+    }
+
+    m := value.(*types.Map).Get()
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys, m
+}
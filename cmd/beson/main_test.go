@@ -0,0 +1,72 @@
+package main
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+
+    "beson"
+    "beson/types"
+)
+
+var sample = beson.Serialize(types.NewMap(map[string]types.RootType {
+    "apple":    types.NewUInt8(2),
+    "banana":   types.NewSlice([]types.RootType { types.NewBool(false), types.NewString("x") }),
+}))
+
+func TestRun(t *testing.T) {
+    t.Run("dump", testRunFunc([]string{ "dump" }, sample, "map (2)\n    \"apple\": uint8 2\n    \"banana\": array (2)\n        [0] false false\n        [1] string \"x\"\n"))
+    t.Run("tojson", testRunFunc([]string{ "tojson", "-extended" }, sample, `{"apple":{"$uint8":2},"banana":[false,"x"]}` + "\n"))
+    t.Run("fromjson", testRunFunc([]string{ "fromjson", "-extended", "-" }, []byte(`{"apple":{"$uint8":2},"banana":[false,"x"]}`), string(sample)))
+    t.Run("validate", testRunFunc([]string{ "validate", "-canonical" }, sample, "ok\n"))
+}
+
+func testRunFunc(args []string, input []byte, expect string) func(*testing.T) {
+    return func(t *testing.T) {
+        var out bytes.Buffer
+        err := run(args, bytes.NewReader(input), &out)
+        if err == nil && out.String() == expect {
+            t.Log("Run test passed.")
+        } else {
+            t.Errorf("Run test failed: %q %v", out.String(), err)
+        }
+    }
+}
+
+func TestHexdump(t *testing.T) {
+    var out bytes.Buffer
+    if err := run([]string{ "hexdump" }, bytes.NewReader(sample), &out); err != nil {
+        t.Fatalf("hexdump failed: %v", err)
+    }
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+    if !strings.HasPrefix(lines[0], "00000000  09 00") || !strings.HasSuffix(lines[0], "header map") {
+        t.Errorf("Unexpected first line: %q", lines[0])
+    }
+    if !strings.HasSuffix(lines[4], `key "apple"`) {
+        t.Errorf("Unexpected key line: %q", lines[4])
+    }
+
+    if err := run([]string{ "validate" }, bytes.NewReader(sample[:len(sample) - 1]), &out); err == nil {
+        t.Error("validate should reject truncated input.")
+    }
+    if err := run([]string{ "hexdump" }, bytes.NewReader(sample[:len(sample) - 1]), &out); err == nil {
+        t.Error("hexdump should reject truncated input.")
+    }
+
+    truncated := map[string][]byte {
+        "scalar":       { 3, 0, 1 },
+        "string":       { 5, 0, 9, 0, 0, 0, 'a', 'b' },
+        "container":    { 7, 0, 3, 0 },
+    }
+    for name, input := range truncated {
+        out.Reset()
+        if err := run([]string{ "hexdump" }, bytes.NewReader(input), &out); err == nil {
+            t.Errorf("hexdump should reject a truncated %s.", name)
+        }
+        for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+            if line != "" && !strings.HasPrefix(line, "00000000") {
+                t.Errorf("hexdump printed past a truncated %s: %q", name, line)
+            }
+        }
+    }
+}
@@ -4,6 +4,9 @@ import (
     "bytes"
 )
 
+// DATA_TYPE maps the name of every built-in type to Kind.String.
+//
+// Deprecated: use the Kind constants.
 var DATA_TYPE = map[string]string {
     "NULL":             "null",
     "FALSE":            "false",
@@ -12,12 +15,14 @@ var DATA_TYPE = map[string]string {
     "INT32":            "int32",
     "INT64":            "int64",
     "INT128":           "int128",
+    "INT256":           "int256",
     "INT8":             "int8",
     "INT16":            "int16",
     
     "UINT32":           "uint32",
     "UINT64":           "uint64",
     "UINT128":          "uint128",
+    "UINT256":          "uint256",
     "UINT8":            "uint8",
     "UINT16":           "uint16",
     
@@ -49,6 +54,9 @@ var DATA_TYPE = map[string]string {
     "SPECIAL_BUFFER":   "special_buffer",
 }
 
+// TYPE_HEADER maps the name of every built-in type to Kind.Header.
+//
+// Deprecated: use the Kind constants.
 var TYPE_HEADER = map[string][]uint8 {
     "NULL":             { 0x00, 0x00 },
     "FALSE":            { 0x01, 0x00 },
@@ -57,12 +65,14 @@ var TYPE_HEADER = map[string][]uint8 {
     "INT32":            { 0x02, 0x00 },
     "INT64":            { 0x02, 0x01 },
     "INT128":           { 0x02, 0x02 },
+    "INT256":           { 0x02, 0x03 },
     "INT8":             { 0x02, 0x04 },
     "INT16":            { 0x02, 0x05 },
     
     "UINT32":           { 0x03, 0x00 },
     "UINT64":           { 0x03, 0x01 },
     "UINT128":          { 0x03, 0x02 },
+    "UINT256":          { 0x03, 0x03 },
     "UINT8":            { 0x03, 0x04 },
     "UINT16":           { 0x03, 0x05 },
     
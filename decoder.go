@@ -0,0 +1,204 @@
+package beson
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "io"
+
+    "beson/types"
+)
+
+// payloadSize lists the fixed payload size of every type that carries no
+// length prefix.
+var payloadSize = map[Kind]uint32 {
+    KindNull:      0,
+    KindFalse:     0,
+    KindTrue:      0,
+    KindInt8:      1,
+    KindUInt8:     1,
+    KindInt16:     2,
+    KindUInt16:    2,
+    KindInt32:     4,
+    KindUInt32:    4,
+    KindFloat32:   4,
+    KindInt64:     8,
+    KindUInt64:    8,
+    KindFloat64:   8,
+    KindDate:      8,
+    KindObjectId:  12,
+    KindInt128:    16,
+    KindUInt128:   16,
+    KindInt256:    32,
+    KindUInt256:   32,
+}
+
+// Decoder reads consecutive top-level beson values from an io.Reader. Only
+// the bytes of the value being decoded are held in memory, and the limits
+// of its options are checked before they are read.
+type Decoder struct {
+    r       *bufio.Reader
+    buf     bytes.Buffer
+    offset  int64
+    opts    Options
+    limits  DecodeOptions
+    depth   int
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+    return NewDecoderWithOptions(r, Options{})
+}
+
+func NewDecoderWithOptions(r io.Reader, opts Options) *Decoder {
+    return &Decoder { r: bufio.NewReader(r), opts: opts, limits: opts.limits() }
+}
+
+// Decode reads and returns the next value. It returns io.EOF when the
+// reader is exhausted between two values and io.ErrUnexpectedEOF when it
+// ends in the middle of one.
+func (dec *Decoder) Decode() (types.RootType, error) {
+    dec.buf.Reset()
+    dec.depth = 0
+
+    if _, err := dec.r.Peek(1); err != nil {
+        return nil, err
+    }
+    if err := dec.readValue(); err != nil {
+        if err == io.EOF {
+            err = io.ErrUnexpectedEOF
+        }
+        return nil, err
+    }
+
+    _, value, err := DeserializeWithOptions(dec.buf.Bytes(), 0, dec.opts)
+    if err != nil {
+        var derr *DeserializeError
+        if errors.As(err, &derr) {
+            err = &DeserializeError { Offset: dec.offset + derr.Offset, Err: derr.Err }
+        }
+        return nil, err
+    }
+
+    dec.offset += int64(dec.buf.Len())
+    return value, nil
+}
+
+// Offset returns the number of bytes consumed by successfully decoded
+// values.
+func (dec *Decoder) Offset() int64 {
+    return dec.offset
+}
+
+// errorAt reports err at position pos of the value being read.
+func (dec *Decoder) errorAt(err error, pos uint32) error {
+    return &DeserializeError { Offset: dec.offset + int64(pos), Err: err }
+}
+
+// readValue copies one complete value, header included, from the reader
+// into dec.buf.
+func (dec *Decoder) readValue() error {
+    start := uint32(dec.buf.Len())
+    if err := dec.readN(2); err != nil {
+        return err
+    }
+
+    t := getTypeHeaderKey(dec.buf.Bytes()[start:start + 2])
+    if t == KindInvalid {
+        return dec.errorAt(ErrUnknownType, start)
+    }
+    return dec.readPayload(t)
+}
+
+func (dec *Decoder) readPayload(t Kind) error {
+    if size, ok := payloadSize[t]; ok {
+        return dec.readN(size)
+    }
+
+    switch t {
+    case KindArrayStart:
+        return dec.readStream(KindArrayEnd, false)
+    case KindMapStart:
+        return dec.readStream(KindMapEnd, true)
+    case KindArrayEnd, KindMapEnd:
+        return dec.errorAt(ErrUnexpectedEnd, uint32(dec.buf.Len()) - 2)
+    }
+
+    // Every remaining type carries a 4 byte length prefix.
+    start := uint32(dec.buf.Len())
+    length, err := dec.readLength(4)
+    if err != nil {
+        return err
+    }
+    if max, limitErr := lengthLimit(t, dec.limits); max > 0 && length > max {
+        return dec.errorAt(limitErr, start)
+    }
+    return dec.readN(length)
+}
+
+// readStream copies the entries of a delimited container up to its end
+// marker. Map entries carry a short string key after their header.
+func (dec *Decoder) readStream(endType Kind, keyed bool) error {
+    dec.depth++
+    defer func() { dec.depth-- }()
+    if dec.limits.MaxDepth > 0 && dec.depth > dec.limits.MaxDepth {
+        return dec.errorAt(ErrMaxDepth, uint32(dec.buf.Len()) - 2)
+    }
+
+    for {
+        start := uint32(dec.buf.Len())
+        if err := dec.readN(2); err != nil {
+            return err
+        }
+
+        t := getTypeHeaderKey(dec.buf.Bytes()[start:start + 2])
+        if t == KindInvalid {
+            return dec.errorAt(ErrUnknownType, start)
+        }
+        if t == endType {
+            return nil
+        }
+
+        if keyed {
+            length, err := dec.readLength(2)
+            if err != nil {
+                return err
+            }
+            if err := dec.readN(length); err != nil {
+                return err
+            }
+        }
+        if err := dec.readPayload(t); err != nil {
+            return err
+        }
+    }
+}
+
+func (dec *Decoder) readLength(size uint32) (uint32, error) {
+    start := uint32(dec.buf.Len())
+    if err := dec.readN(size); err != nil {
+        return 0, err
+    }
+
+    b := dec.buf.Bytes()[start:start + size]
+    if size == 2 {
+        return uint32(binary.LittleEndian.Uint16(b)), nil
+    }
+    return binary.LittleEndian.Uint32(b), nil
+}
+
+func (dec *Decoder) readN(n uint32) error {
+    size := uint64(dec.buf.Len()) + uint64(n)
+    if max := dec.limits.MaxTotalBytes; max > 0 && size > uint64(max) {
+        return dec.errorAt(ErrMaxTotalBytes, max)
+    }
+
+    copied, err := io.CopyN(&dec.buf, dec.r, int64(n))
+    if copied < int64(n) {
+        if err == nil || err == io.EOF {
+            err = io.ErrUnexpectedEOF
+        }
+        return err
+    }
+    return nil
+}
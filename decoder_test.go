@@ -0,0 +1,80 @@
+package beson
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "reflect"
+    "testing"
+
+    "beson/types"
+)
+
+func TestDecoder(t *testing.T) {
+    keys := []string{ "NULL", "UINT128", "STRING", "ARRAY", "MAP", "BINARY", "DATE", "OBJECTID", "INT16_ARRAY" }
+
+    var stream bytes.Buffer
+    for _, key := range keys {
+        stream.Write(serializedData[key])
+    }
+    // A delimited array as produced by Encoder.OpenArray.
+    stream.Write([]byte{ 7, 0, 3, 4, 2, 5, 0, 1, 0, 0, 0, 97, 8, 0 })
+
+    dec := NewDecoder(&stream)
+    for _, key := range keys {
+        value, err := dec.Decode()
+        if err != nil || !types.Equal(value, originData[key], types.EqualOptions{}) {
+            t.Errorf("Decode %s failed: %v", key, err)
+        }
+    }
+
+    value, err := dec.Decode()
+    expect := types.NewSlice([]types.RootType{ types.NewUInt8(2), types.NewString("a") })
+    if err != nil || !types.Equal(value, expect, types.EqualOptions{}) {
+        t.Errorf("Decode ARRAY_START failed: %v", err)
+    }
+
+    if _, err := dec.Decode(); err != io.EOF {
+        t.Errorf("Decode should return io.EOF, got %v", err)
+    }
+}
+
+func TestDecoderErrors(t *testing.T) {
+    truncated := serializedData["STRING"][:10]
+    if _, err := NewDecoder(bytes.NewReader(truncated)).Decode(); err != io.ErrUnexpectedEOF {
+        t.Errorf("Truncated value should return io.ErrUnexpectedEOF, got %v", err)
+    }
+
+    stream := append(append([]byte{}, serializedData["UINT8"]...), 0x7f, 0x7f)
+    dec := NewDecoder(bytes.NewReader(stream))
+    dec.Decode()
+    _, err := dec.Decode()
+    var derr *DeserializeError
+    if !errors.As(err, &derr) || derr.Err != ErrUnknownType || derr.Offset != 3 {
+        t.Errorf("Unknown header should be reported at offset 3, got %v", err)
+    }
+}
+
+func TestDecoderLargeOffset(t *testing.T) {
+    // Offsets of streams past 4 GiB must not wrap around.
+    stream := append(append([]byte{}, serializedData["UINT8"]...), 0x7f, 0x7f)
+    dec := NewDecoder(bytes.NewReader(stream))
+    dec.offset = 1 << 32 - 1
+    dec.Decode()
+    if dec.Offset() != 1 << 32 + 2 {
+        t.Errorf("Offset should go past 4 GiB, got %d", dec.Offset())
+    }
+    _, err := dec.Decode()
+    var derr *DeserializeError
+    if !errors.As(err, &derr) || derr.Offset != 1 << 32 + 2 {
+        t.Errorf("Error offset should go past 4 GiB, got %v", err)
+    }
+}
+
+func TestDecoderOptions(t *testing.T) {
+    data := []byte{ 9, 0, 10, 0, 0, 0, 0, 0, 1, 0, 98, 0, 0, 1, 0, 97 }
+    value, err := NewDecoderWithOptions(bytes.NewReader(data), Options { OrderedMaps: true }).Decode()
+    if err != nil || !reflect.DeepEqual(value.(*types.OrderedMap).Keys(), []string{ "b", "a" }) {
+        t.Errorf("Decode into OrderedMap failed: %v", err)
+    }
+}
@@ -3,138 +3,202 @@ package beson
 import (
     "encoding/binary"
     "math"
-    "strings"
+    "time"
 
     "beson/types"
 )
 
+// depthOnly returns the options of Deserialize and DeserializeE. They
+// predate DecodeOptions and keep accepting values of any size, but nesting
+// is still bounded by DefaultDecodeOptions.MaxDepth so that hostile input
+// cannot overflow the stack.
+func depthOnly() Options {
+    return Options { Limits: &DecodeOptions { MaxDepth: DefaultDecodeOptions.MaxDepth } }
+}
+
 func Deserialize(buffer []byte, anchor uint32)(uint32, types.RootType) {
-    return deserializeContent(buffer, anchor)
+    end, value, err := deserializeValue(buffer, anchor, depthOnly())
+    if err != nil {
+        return anchor, nil
+    }
+    return end, value
+}
+
+// DeserializeE behaves like Deserialize but reports malformed input as a
+// *DeserializeError instead of panicking or yielding a nil value. Like
+// Deserialize it only limits the nesting depth, use DeserializeWithOptions
+// to bound the size of untrusted input.
+func DeserializeE(buffer []byte, anchor uint32)(uint32, types.RootType, error) {
+    return deserializeValue(buffer, anchor, depthOnly())
 }
 
-func deserializeContent(buffer []byte, start uint32)(uint32, types.RootType) {
+// DeserializeWithOptions is DeserializeE with decoding options. In
+// canonical mode maps holding the same key twice are rejected. Unless
+// opts.Limits says otherwise DefaultDecodeOptions apply.
+func DeserializeWithOptions(buffer []byte, anchor uint32, opts Options)(uint32, types.RootType, error) {
+    return deserializeValue(buffer, anchor, opts)
+}
+
+// deserializeValue decodes the top-level value at start, enforcing the
+// limits of opts.
+func deserializeValue(buffer []byte, start uint32, opts Options)(uint32, types.RootType, error) {
+    st := newDecodeState(opts, start)
+    end, value, err := deserializeContent(buffer, start, st)
+    if err != nil {
+        return start, nil, err
+    }
+    if err := st.checkTotal(end); err != nil {
+        return start, nil, err
+    }
+    return end, value, nil
+}
+
+func deserializeContent(buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
     var anchor uint32
-    var t string
+    var t Kind
     var value types.RootType
+    var err error
 
-    anchor, t = deserializeType(buffer, start)
-    anchor, value = deserializeData(t, buffer, anchor)
+    anchor, t, err = deserializeType(buffer, start)
+    if err != nil {
+        return start, nil, err
+    }
+    anchor, value, err = deserializeData(t, buffer, anchor, st)
+    if err != nil {
+        return start, nil, err
+    }
 
-    return anchor, value
+    return anchor, value, nil
 }
 
-func deserializeType(buffer []byte, start uint32)(uint32, string) {
+func deserializeType(buffer []byte, start uint32)(uint32, Kind, error) {
     var length uint32 = 2
+    if err := checkBounds(buffer, start, length); err != nil {
+        return start, KindInvalid, err
+    }
     end := start + length
     typeData := buffer[start:end]
     
     t := getTypeHeaderKey(typeData)
-    return end, t
+    if t == KindInvalid {
+        return start, KindInvalid, newDeserializeError(ErrUnknownType, start)
+    }
+    return end, t, nil
 }
 
-func deserializeData(t string , buffer []byte, start uint32)(uint32, types.RootType) {
-    var anchor uint32
-    var value types.RootType
+// deserializeData decodes the payload of type t at start, dispatching on
+// the header through the decoders table.
+func deserializeData(t Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    if err := st.element(start - 2); err != nil {
+        return start, nil, err
+    }
 
-    switch t {
-    case DATA_TYPE["NULL"]:
-        anchor, value = deserializeNull(start)
-    case DATA_TYPE["TRUE"], DATA_TYPE["FALSE"]:
-        anchor, value = deserializeBoolean(t, start)
-    case DATA_TYPE["INT8"]:
-        anchor, value = deserializeInt8(buffer, start)
-    case DATA_TYPE["INT16"]:
-        anchor, value = deserializeInt16(buffer, start)
-    case DATA_TYPE["INT32"]:
-        anchor, value = deserializeInt32(buffer, start)
-    case DATA_TYPE["INT64"]:
-        anchor, value = deserializeInt64(buffer, start)
-    case DATA_TYPE["INT128"]:
-        anchor, value = deserializeInt128(buffer, start)
-    case DATA_TYPE["UINT8"]:
-        anchor, value = deserializeUInt8(buffer, start)
-    case DATA_TYPE["UINT16"]:
-        anchor, value = deserializeUInt16(buffer, start)
-    case DATA_TYPE["UINT32"]:
-        anchor, value = deserializeUInt32(buffer, start)
-    case DATA_TYPE["UINT64"]:
-        anchor, value = deserializeUInt64(buffer, start)
-    case DATA_TYPE["UINT128"]:
-        anchor, value = deserializeUInt128(buffer, start)
-    case DATA_TYPE["FLOAT32"]:
-        anchor, value = deserializeFloat32(buffer, start)
-    case DATA_TYPE["FLOAT64"]:
-        anchor, value = deserializeFloat64(buffer, start)
-    case DATA_TYPE["STRING"]:
-        anchor, value = deserializeString(buffer, start)
-    case DATA_TYPE["ARRAY"]:
-        anchor, value = deserializeSlice(buffer, start)
-    case DATA_TYPE["MAP"]:
-        anchor, value = deserializeMap(buffer, start)
-    case DATA_TYPE["BINARY"]:
-        anchor, value = deserializeBinary(buffer, start)
-    }
-    return anchor, value
-}
-
-func getTypeHeaderKey(typeData []uint8) string {
-    var t string
-    for key, value := range TYPE_HEADER {
-        if value[0] == typeData[0] && value[1] == typeData[1] {
-            t = strings.ToLower(key)
-            break
+    if t.IsExtension() {
+        return deserializeExtension(t, buffer, start, st)
+    }
+    if t < 0x1000 {
+        if decode := decoders[t >> 8][t & 0xff]; decode != nil {
+            return decode(t, buffer, start, st)
         }
     }
-    return t
+    return start, nil, newDeserializeError(ErrUnknownType, start - 2)
+}
+
+func getTypeHeaderKey(typeData []uint8) Kind {
+    return kindOfHeader(typeData)
+}
+
+// checkBounds verifies that length bytes starting at start are available.
+func checkBounds(buffer []byte, start uint32, length uint32) error {
+    if uint64(start) + uint64(length) > uint64(len(buffer)) {
+        return newDeserializeError(ErrTruncated, start)
+    }
+    return nil
+}
+
+// readLengthPrefix reads a size-byte little endian length prefix at start
+// and returns the payload range it describes.
+func readLengthPrefix(buffer []byte, start uint32, size uint32)(uint32, uint32, error) {
+    if err := checkBounds(buffer, start, size); err != nil {
+        return start, start, err
+    }
+
+    var length uint32
+    if size == 2 {
+        length = uint32(binary.LittleEndian.Uint16(buffer[start:start + 2]))
+    } else {
+        length = binary.LittleEndian.Uint32(buffer[start:start + 4])
+    }
+
+    begin := start + size
+    if uint64(begin) + uint64(length) > uint64(len(buffer)) {
+        return start, start, newDeserializeError(ErrLengthOverflow, start)
+    }
+    return begin, begin + length, nil
 }
 
-func deserializeNull(start uint32)(uint32, types.RootType) {
-    return start, nil
+func deserializeNull(start uint32)(uint32, types.RootType, error) {
+    return start, nil, nil
 }
 
-func deserializeBoolean(t string, start uint32)(uint32, types.RootType) {
+func deserializeBoolean(t Kind, start uint32)(uint32, types.RootType, error) {
     var value *types.Bool
-    if t == DATA_TYPE["TRUE"] {
-        value = types.NewBool(true).(*types.Bool)
+    if t == KindTrue {
+        value = types.NewBool(true)
     } else {
-        value = types.NewBool(false).(*types.Bool)
+        value = types.NewBool(false)
     }
-    return start, value
+    return start, value, nil
 }
 
-func deserializeInt8(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeInt8(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 1); err != nil {
+        return start, nil, err
+    }
     end := start + 1
     num := int8(buffer[start])
-    value := types.NewInt8(num).(*types.Int8)
+    value := types.NewInt8(num)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeInt16(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeInt16(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 2); err != nil {
+        return start, nil, err
+    }
     end := start + 2
     num := binary.LittleEndian.Uint16(buffer[start:end])
-    value := types.NewInt16(int16(num)).(*types.Int16)
+    value := types.NewInt16(int16(num))
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeInt32(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeInt32(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 4); err != nil {
+        return start, nil, err
+    }
     end := start + 4
     num := binary.LittleEndian.Uint32(buffer[start:end])
-    value := types.NewInt32(int32(num)).(*types.Int32)
+    value := types.NewInt32(int32(num))
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeInt64(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeInt64(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 8); err != nil {
+        return start, nil, err
+    }
     end := start + 8
     num := binary.LittleEndian.Uint64(buffer[start:end])
-    value := types.NewInt64(int64(num)).(*types.Int64)
+    value := types.NewInt64(int64(num))
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeInt128(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeInt128(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 16); err != nil {
+        return start, nil, err
+    }
     end := start + 16
     numLow := binary.LittleEndian.Uint64(buffer[start:start + 8])
     numHigh := binary.LittleEndian.Uint64(buffer[start + 8:end])
@@ -143,42 +207,67 @@ func deserializeInt128(buffer []byte, start uint32)(uint32, types.RootType) {
     value.SetLow(numLow)
     value.SetHigh(numHigh)
 
-    return end, value
+    return end, value, nil
+}
+
+func deserializeInt256(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 32); err != nil {
+        return start, nil, err
+    }
+    end := start + 32
+    value := types.Int256FromBytes(buffer[start:end])
+
+    return end, value, nil
 }
 
-func deserializeUInt8(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeUInt8(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 1); err != nil {
+        return start, nil, err
+    }
     end := start + 1
     num := buffer[start]
-    value := types.NewUInt8(num).(*types.UInt8)
+    value := types.NewUInt8(num)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeUInt16(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeUInt16(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 2); err != nil {
+        return start, nil, err
+    }
     end := start + 2
     num := binary.LittleEndian.Uint16(buffer[start:end])
-    value := types.NewUInt16(num).(*types.UInt16)
+    value := types.NewUInt16(num)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeUInt32(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeUInt32(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 4); err != nil {
+        return start, nil, err
+    }
     end := start + 4
     num := binary.LittleEndian.Uint32(buffer[start:end])
-    value := types.NewUInt32(num).(*types.UInt32)
+    value := types.NewUInt32(num)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeUInt64(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeUInt64(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 8); err != nil {
+        return start, nil, err
+    }
     end := start + 8
     num := binary.LittleEndian.Uint64(buffer[start:end])
-    value := types.NewUInt64(num).(*types.UInt64)
+    value := types.NewUInt64(num)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeUInt128(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeUInt128(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 16); err != nil {
+        return start, nil, err
+    }
     end := start + 16
     numLow := binary.LittleEndian.Uint64(buffer[start:start + 8])
     numHigh := binary.LittleEndian.Uint64(buffer[start + 8:end])
@@ -188,89 +277,354 @@ func deserializeUInt128(buffer []byte, start uint32)(uint32, types.RootType) {
     value.SetLow(numLow)
     value.SetHigh(numHigh)
 
-    return end, value
+    return end, value, nil
+}
+
+func deserializeUInt256(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 32); err != nil {
+        return start, nil, err
+    }
+    end := start + 32
+    value := types.UInt256FromBytes(buffer[start:end])
+
+    return end, value, nil
 }
 
-func deserializeFloat32(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeFloat32(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 4); err != nil {
+        return start, nil, err
+    }
     end := start + 4
     numUint32 := binary.LittleEndian.Uint32(buffer[start:end])
     num := math.Float32frombits(numUint32)
-    value := types.NewFloat32(num).(*types.Float32)
+    value := types.NewFloat32(num)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeFloat64(buffer []byte, start uint32)(uint32, types.RootType) {
+func deserializeFloat64(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 8); err != nil {
+        return start, nil, err
+    }
     end := start + 8
     numUint64 := binary.LittleEndian.Uint64(buffer[start:end])
     num := math.Float64frombits(numUint64)
-    value := types.NewFloat64(num).(*types.Float64)
+    value := types.NewFloat64(num)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeString(buffer []byte, start uint32)(uint32, types.RootType) {
-    length := binary.LittleEndian.Uint32(buffer[start:start + 4])
-    end := start + 4 + length
-    str := string(buffer[start + 4:end])
-    value := types.NewString(str).(*types.String)
+func deserializeString(buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    begin, end, err := st.readLimitedLength(KindString, buffer, start)
+    if err != nil {
+        return start, nil, err
+    }
+    str := string(buffer[begin:end])
+    value := types.NewString(str)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeShortString(buffer []byte, start uint32)(uint32, types.RootType) {
-    length := binary.LittleEndian.Uint16(buffer[start:start + 2])
-    end := start + 2 + uint32(length)
-    str := string(buffer[start + 2:end])
-    value := types.NewString(str).(*types.String)
+func deserializeShortString(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    begin, end, err := readLengthPrefix(buffer, start, 2)
+    if err != nil {
+        return start, nil, err
+    }
+    str := string(buffer[begin:end])
+    value := types.NewString(str)
 
-    return end, value
+    return end, value, nil
 }
 
-func deserializeSlice(buffer []byte, start uint32)(uint32, types.RootType) {
-    length := binary.LittleEndian.Uint32(buffer[start:start + 4])
-    start = start + 4
-    end := start + length
+func deserializeSlice(buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    if err := st.enter(start - 2); err != nil {
+        return start, nil, err
+    }
+    defer st.leave()
+
+    begin, end, err := st.readLimitedLength(KindArray, buffer, start)
+    if err != nil {
+        return start, nil, err
+    }
+    // Children must not read past the declared end of the container.
+    container := buffer[:end]
     slice := []types.RootType{}
 
-    for start < end {
-        var subType string
+    for anchor := begin; anchor < end; {
+        var subType Kind
         var subData types.RootType
-        start, subType = deserializeType(buffer, start)
-        start, subData = deserializeData(subType, buffer, start)
+        anchor, subType, err = deserializeType(container, anchor)
+        if err != nil {
+            return start, nil, err
+        }
+        anchor, subData, err = deserializeData(subType, container, anchor, st)
+        if err != nil {
+            return start, nil, err
+        }
         slice = append(slice, subData)
     }
 
-    value := types.NewSlice(slice).(*types.Slice)
-    return end, value
+    value := types.NewSlice(slice)
+    return end, value, nil
 }
 
-func deserializeMap(buffer []byte, start uint32)(uint32, types.RootType) {
-    length := binary.LittleEndian.Uint32(buffer[start:start + 4])
-    start = start + 4
-    end := start + length
-    m := map[string]types.RootType{}
+func deserializeMap(buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    if err := st.enter(start - 2); err != nil {
+        return start, nil, err
+    }
+    defer st.leave()
+
+    begin, end, err := st.readLimitedLength(KindMap, buffer, start)
+    if err != nil {
+        return start, nil, err
+    }
+    container := buffer[:end]
+    m := newMapBuilder(st.opts)
 
-    for start < end {
-        var subType string
+    for anchor := begin; anchor < end; {
+        var subType Kind
         var subKey types.RootType
         var subData types.RootType
-        start, subType = deserializeType(buffer, start)
-        start, subKey = deserializeShortString(buffer, start)
-        start, subData = deserializeData(subType, buffer, start)
-        m[subKey.(*types.String).Get()] = subData
+        anchor, subType, err = deserializeType(container, anchor)
+        if err != nil {
+            return start, nil, err
+        }
+        keyStart := anchor
+        anchor, subKey, err = deserializeShortString(container, anchor)
+        if err != nil {
+            return start, nil, err
+        }
+        if m.has(subKey.(*types.String).Get()) && st.opts.Canonical {
+            return start, nil, newDeserializeError(ErrDuplicateKey, keyStart)
+        }
+        anchor, subData, err = deserializeData(subType, container, anchor, st)
+        if err != nil {
+            return start, nil, err
+        }
+        m.set(subKey.(*types.String).Get(), subData)
     }
 
-    value := types.NewMap(m).(*types.Map)
-    return end, value
+    value := m.value()
+    return end, value, nil
 }
 
-func deserializeBinary(buffer []byte, start uint32)(uint32, types.RootType) {
-    length := binary.LittleEndian.Uint32(buffer[start:start + 4])
-    end := start + 4 + length
-    bs := buffer[start + 4:end]
+// deserializeSliceStream decodes the elements following an ARRAY_START
+// header up to the matching ARRAY_END marker.
+func deserializeSliceStream(buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    if err := st.enter(start - 2); err != nil {
+        return start, nil, err
+    }
+    defer st.leave()
+
+    slice := []types.RootType{}
+    anchor := start
+
+    for {
+        var subType Kind
+        var subData types.RootType
+        var err error
+        anchor, subType, err = deserializeType(buffer, anchor)
+        if err != nil {
+            return start, nil, err
+        }
+        if subType == KindArrayEnd {
+            break
+        }
+        anchor, subData, err = deserializeData(subType, buffer, anchor, st)
+        if err != nil {
+            return start, nil, err
+        }
+        slice = append(slice, subData)
+    }
+
+    value := types.NewSlice(slice)
+    return anchor, value, nil
+}
+
+// deserializeMapStream decodes the entries following a MAP_START header up
+// to the matching MAP_END marker.
+func deserializeMapStream(buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    if err := st.enter(start - 2); err != nil {
+        return start, nil, err
+    }
+    defer st.leave()
+
+    m := newMapBuilder(st.opts)
+    anchor := start
+
+    for {
+        var subType Kind
+        var subKey types.RootType
+        var subData types.RootType
+        var err error
+        anchor, subType, err = deserializeType(buffer, anchor)
+        if err != nil {
+            return start, nil, err
+        }
+        if subType == KindMapEnd {
+            break
+        }
+        keyStart := anchor
+        anchor, subKey, err = deserializeShortString(buffer, anchor)
+        if err != nil {
+            return start, nil, err
+        }
+        if m.has(subKey.(*types.String).Get()) && st.opts.Canonical {
+            return start, nil, newDeserializeError(ErrDuplicateKey, keyStart)
+        }
+        anchor, subData, err = deserializeData(subType, buffer, anchor, st)
+        if err != nil {
+            return start, nil, err
+        }
+        m.set(subKey.(*types.String).Get(), subData)
+    }
+
+    value := m.value()
+    return anchor, value, nil
+}
+
+// mapBuilder collects decoded map entries into a *types.Map, or into a
+// *types.OrderedMap when the options ask for wire order.
+type mapBuilder struct {
+    m       map[string]types.RootType
+    ordered *types.OrderedMap
+}
+
+func newMapBuilder(opts Options) *mapBuilder {
+    if opts.OrderedMaps {
+        return &mapBuilder { ordered: types.NewOrderedMap() }
+    }
+    return &mapBuilder { m: map[string]types.RootType{} }
+}
+
+func (b *mapBuilder) has(key string) bool {
+    if b.ordered != nil {
+        _, ok := b.ordered.Get(key)
+        return ok
+    }
+    _, ok := b.m[key]
+    return ok
+}
+
+func (b *mapBuilder) set(key string, value types.RootType) {
+    if b.ordered != nil {
+        b.ordered.Set(key, value)
+    } else {
+        b.m[key] = value
+    }
+}
+
+func (b *mapBuilder) value() types.RootType {
+    if b.ordered != nil {
+        return b.ordered
+    }
+    return types.NewMap(b.m)
+}
+
+func deserializeBinary(buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    begin, end, err := st.readLimitedLength(KindBinary, buffer, start)
+    if err != nil {
+        return start, nil, err
+    }
+    bs := buffer[begin:end]
     bin := types.NewBinary(0).(*types.Binary)
     value := bin.FromBytes(bs)
 
-    return end, value
+    return end, value, nil
+}
+
+// deserializeSpecialBuffer reads the subtype and bytes counted by the
+// length. Subtypes are not checked, unknown ones are kept as they are.
+func deserializeSpecialBuffer(buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    begin, end, err := st.readLimitedLength(KindSpecialBuffer, buffer, start)
+    if err != nil {
+        return start, nil, err
+    }
+    if begin == end {
+        return start, nil, newDeserializeError(ErrTruncated, begin)
+    }
+    bs := make([]byte, end - begin - 1)
+    copy(bs, buffer[begin + 1:end])
+
+    return end, types.NewSpecialBuffer(buffer[begin], bs), nil
+}
+
+func deserializeDate(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    if err := checkBounds(buffer, start, 8); err != nil {
+        return start, nil, err
+    }
+    end := start + 8
+    numUint64 := binary.LittleEndian.Uint64(buffer[start:end])
+    ms := math.Float64frombits(numUint64)
+
+    // JavaScript encodes an invalid Date as NaN, map it to the zero time.
+    var value *types.Date
+    if math.IsNaN(ms) || math.IsInf(ms, 0) {
+        value = types.NewDate(time.Time{})
+    } else {
+        value = types.NewDateFromMillis(int64(ms))
+    }
+
+    return end, value, nil
+}
+
+func deserializeObjectId(buffer []byte, start uint32)(uint32, types.RootType, error) {
+    size := uint32(types.OBJECTID_SIZE)
+    if err := checkBounds(buffer, start, size); err != nil {
+        return start, nil, err
+    }
+    end := start + size
+    value, _ := types.ObjectIdFromBytes(buffer[start:end])
+
+    return end, value, nil
+}
+
+var typedArrayElementSize = map[Kind]uint32 {
+    KindArrayBuffer:  1,
+    KindDataView:     1,
+    KindUInt8Array:   1,
+    KindInt8Array:    1,
+    KindUInt16Array:  2,
+    KindInt16Array:   2,
+    KindUInt32Array:  4,
+    KindInt32Array:   4,
+    KindFloat32Array: 4,
+    KindFloat64Array: 8,
+}
+
+func deserializeTypedArray(t Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    begin, end, err := st.readLimitedLength(t, buffer, start)
+    if err != nil {
+        return start, nil, err
+    }
+    if (end - begin) % typedArrayElementSize[t] != 0 {
+        return start, nil, newDeserializeError(ErrInvalidLength, start)
+    }
+
+    bs := buffer[begin:end]
+    var value types.RootType
+    switch t {
+    case KindArrayBuffer:
+        value = types.ArrayBufferFromBytes(bs)
+    case KindDataView:
+        value = types.DataViewFromBytes(bs)
+    case KindUInt8Array:
+        value = types.UInt8ArrayFromBytes(bs)
+    case KindInt8Array:
+        value = types.Int8ArrayFromBytes(bs)
+    case KindUInt16Array:
+        value = types.UInt16ArrayFromBytes(bs)
+    case KindInt16Array:
+        value = types.Int16ArrayFromBytes(bs)
+    case KindUInt32Array:
+        value = types.UInt32ArrayFromBytes(bs)
+    case KindInt32Array:
+        value = types.Int32ArrayFromBytes(bs)
+    case KindFloat32Array:
+        value = types.Float32ArrayFromBytes(bs)
+    case KindFloat64Array:
+        value = types.Float64ArrayFromBytes(bs)
+    }
+
+    return end, value, nil
 }
@@ -0,0 +1,304 @@
+package beson
+
+import (
+    "bufio"
+    "encoding/binary"
+    "io"
+    "reflect"
+
+    "beson/types"
+)
+
+// Encoder writes beson values to an io.Writer. Headers and payloads are
+// written straight into a buffered writer, call Flush once done. Besides
+// whole values it can emit delimited containers (ARRAY_START ... ARRAY_END,
+// MAP_START ... MAP_END) element by element, so their size need not be
+// known up front.
+//
+//     enc := beson.NewEncoder(w)
+//     enc.OpenMap()
+//     enc.Key("samples")
+//     enc.OpenArray()
+//     for _, s := range samples {
+//         enc.Encode(types.NewFloat64(s))
+//     }
+//     enc.Close()
+//     enc.Close()
+//     enc.Flush()
+type Encoder struct {
+    w           *bufio.Writer
+    scratch     [8]byte
+    containers  []Kind
+    key         *string
+    err         error
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+    return &Encoder { w: bufio.NewWriter(w) }
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (enc *Encoder) Flush() error {
+    if enc.err != nil {
+        return enc.err
+    }
+    if err := enc.w.Flush(); err != nil {
+        enc.err = err
+    }
+    return enc.err
+}
+
+// Encode writes a complete value. Inside an open map it must be preceded
+// by a call to Key. Values implementing Marshaler are written as the value
+// their MarshalBESON returns, whose error is returned as a MarshalerError.
+func (enc *Encoder) Encode(v types.RootType) error {
+    if _, ok := v.(Marshaler); ok && builtinType(v) == KindInvalid {
+        root, err := ToRootType(v)
+        if err != nil {
+            return err
+        }
+        v = root
+    }
+    t := getType(v)
+    if t == KindInvalid {
+        return &UnsupportedTypeError { Type: reflect.TypeOf(v) }
+    }
+    node := prepare(t, v)
+    if err := enc.writeEntry(serializeType(t)); err != nil {
+        return err
+    }
+    return enc.writeNode(node)
+}
+
+// OpenArray starts a delimited array, closed by Close.
+func (enc *Encoder) OpenArray() error {
+    if err := enc.writeEntry(KindArrayStart.header()); err != nil {
+        return err
+    }
+    enc.containers = append(enc.containers, KindArray)
+    return nil
+}
+
+// OpenMap starts a delimited map, closed by Close. Every entry must be
+// preceded by a call to Key.
+func (enc *Encoder) OpenMap() error {
+    if err := enc.writeEntry(KindMapStart.header()); err != nil {
+        return err
+    }
+    enc.containers = append(enc.containers, KindMap)
+    return nil
+}
+
+// Key sets the key of the next entry of the innermost open map. Each key
+// must be followed by its value before the next key.
+func (enc *Encoder) Key(key string) error {
+    if enc.err != nil {
+        return enc.err
+    }
+    if enc.current() != KindMap {
+        return ErrUnexpectedKey
+    }
+    if enc.key != nil {
+        return ErrKeyWithoutValue
+    }
+    enc.key = &key
+    return nil
+}
+
+// Close writes the end marker of the innermost open container.
+func (enc *Encoder) Close() error {
+    if enc.err != nil {
+        return enc.err
+    }
+
+    var marker []byte
+    switch enc.current() {
+    case KindArray:
+        marker = KindArrayEnd.header()
+    case KindMap:
+        if enc.key != nil {
+            return ErrKeyWithoutValue
+        }
+        marker = KindMapEnd.header()
+    default:
+        return ErrNoOpenContainer
+    }
+
+    enc.containers = enc.containers[:len(enc.containers) - 1]
+    return enc.write(marker)
+}
+
+// Depth returns the number of containers still open.
+func (enc *Encoder) Depth() int {
+    return len(enc.containers)
+}
+
+func (enc *Encoder) current() Kind {
+    if len(enc.containers) == 0 {
+        return KindInvalid
+    }
+    return enc.containers[len(enc.containers) - 1]
+}
+
+// writeEntry writes a type header followed, inside a map, by the pending
+// key.
+func (enc *Encoder) writeEntry(header []byte) error {
+    if enc.err != nil {
+        return enc.err
+    }
+    if enc.current() != KindMap {
+        return enc.write(header)
+    }
+
+    if enc.key == nil {
+        return ErrMissingKey
+    }
+    key := *enc.key
+    enc.key = nil
+    if err := enc.write(header); err != nil {
+        return err
+    }
+    return enc.writeShortString(key)
+}
+
+// encodeNode is a value ready to be written. Containers hold their
+// elements, each marshaled once, and know their payload length before the
+// first byte is written.
+type encodeNode struct {
+    kind        Kind
+    value       interface{}
+    key         string
+    length      uint32
+    elements    []encodeNode
+}
+
+// prepare builds the node of v, whose type is t. Container lengths are
+// summed bottom-up from their elements in a single pass, unsupported
+// elements are left out, as Serialize does.
+func prepare(t Kind, v interface{}) encodeNode {
+    node := encodeNode { kind: t, value: v }
+    switch t {
+    case KindArray:
+        node.length = 4
+        for _, element := range v.(*types.Slice).Get() {
+            if child, ok := prepareElement(element); ok {
+                node.length += 2 + child.length
+                node.elements = append(node.elements, child)
+            }
+        }
+    case KindMap:
+        node.length = 4
+        keys, values := mapEntries(v)
+        for i, key := range keys {
+            if child, ok := prepareElement(values[i]); ok {
+                child.key = key
+                node.length += 2 + 2 + uint32(len(key)) + child.length
+                node.elements = append(node.elements, child)
+            }
+        }
+    default:
+        node.length = dataLength(t, v)
+    }
+    return node
+}
+
+func prepareElement(element interface{}) (encodeNode, bool) {
+    element = marshaled(element)
+    t := getType(element)
+    if t == KindInvalid {
+        return encodeNode{}, false
+    }
+    return prepare(t, element), true
+}
+
+// writeNode writes the payload of node, containers element by element
+// after their length.
+func (enc *Encoder) writeNode(node encodeNode) error {
+    if node.kind != KindArray && node.kind != KindMap {
+        return enc.writeData(node.kind, node.value)
+    }
+
+    enc.writeUint32(node.length - 4)
+    for _, element := range node.elements {
+        enc.write(serializeType(element.kind))
+        if node.kind == KindMap {
+            enc.writeShortString(element.key)
+        }
+        enc.writeNode(element)
+    }
+    return enc.err
+}
+
+// writeData writes the payload of v, which is not a container.
+func (enc *Encoder) writeData(t Kind, v interface{}) error {
+    switch t {
+    case KindString:
+        str := v.(*types.String).Get()
+        enc.writeUint32(uint32(len(str)))
+        return enc.writeString(str)
+    case KindBinary:
+        bs := v.(*types.Binary).ToBytes()
+        enc.writeUint32(uint32(len(bs)))
+        return enc.write(bs)
+    }
+
+    if _, ok := typedArrayElementSize[t]; ok {
+        bs := v.(typedArray).ToBytes()
+        enc.writeUint32(uint32(len(bs)))
+        return enc.write(bs)
+    }
+    return enc.write(serializeData(t, v, DefaultRegistry))
+}
+
+// dataLength returns the number of bytes serializeData produces for v, a
+// value other than a container, length prefix included.
+func dataLength(t Kind, v interface{}) uint32 {
+    if size, ok := payloadSize[t]; ok {
+        return size
+    }
+
+    switch t {
+    case KindString:
+        return 4 + uint32(len(v.(*types.String).Get()))
+    case KindBinary:
+        return 4 + uint32(v.(*types.Binary).Size())
+    }
+
+    if size, ok := typedArrayElementSize[t]; ok {
+        return 4 + size * uint32(v.(interface{ Len() int }).Len())
+    }
+    return uint32(len(serializeData(t, v, DefaultRegistry)))
+}
+
+func (enc *Encoder) writeUint32(n uint32) error {
+    binary.LittleEndian.PutUint32(enc.scratch[:4], n)
+    return enc.write(enc.scratch[:4])
+}
+
+func (enc *Encoder) writeShortString(str string) error {
+    binary.LittleEndian.PutUint16(enc.scratch[:2], uint16(len(str)))
+    enc.write(enc.scratch[:2])
+    return enc.writeString(str)
+}
+
+func (enc *Encoder) writeString(str string) error {
+    if enc.err != nil {
+        return enc.err
+    }
+    if _, err := enc.w.WriteString(str); err != nil {
+        enc.err = err
+    }
+    return enc.err
+}
+
+// write forwards b to the underlying writer. Errors are sticky, the output
+// is unusable once a write failed.
+func (enc *Encoder) write(b []byte) error {
+    if enc.err != nil {
+        return enc.err
+    }
+    if _, err := enc.w.Write(b); err != nil {
+        enc.err = err
+    }
+    return enc.err
+}
@@ -0,0 +1,182 @@
+package beson
+
+import (
+    "bytes"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+
+    "beson/types"
+)
+
+func TestEncoderStream(t *testing.T) {
+    var buf bytes.Buffer
+    enc := NewEncoder(&buf)
+    enc.OpenMap()
+    enc.Key("a")
+    enc.OpenArray()
+    enc.Encode(types.NewInt32(-3))
+    enc.Encode(types.NewFloat32(0.456))
+    enc.Close()
+    enc.Key("b")
+    enc.Encode(types.NewUInt8(2))
+    enc.Close()
+    if err := enc.Flush(); err != nil {
+        t.Fatalf("Encoder failed: %v", err)
+    }
+
+    expect := []byte{
+        10, 0,
+            7, 0, 1, 0, 97,
+                2, 0, 253, 255, 255, 255,
+                4, 1, 213, 120, 233, 62,
+            8, 0,
+            3, 4, 1, 0, 98, 2,
+        11, 0,
+    }
+    if !reflect.DeepEqual(buf.Bytes(), expect) {
+        t.Fatalf("Encoder test failed: %v", buf.Bytes())
+    }
+
+    anchor, value, err := DeserializeE(buf.Bytes(), 0)
+    expectValue := types.NewMap(map[string]types.RootType {
+        "a": types.NewSlice([]types.RootType{ types.NewInt32(-3), types.NewFloat32(0.456) }),
+        "b": types.NewUInt8(2),
+    })
+    if err != nil || anchor != uint32(len(expect)) || !types.Equal(value, expectValue, types.EqualOptions{}) {
+        t.Errorf("Deserialize stream test failed: %v", err)
+    }
+}
+
+func TestEncoderErrors(t *testing.T) {
+    enc := NewEncoder(&bytes.Buffer{})
+    if err := enc.Close(); err != ErrNoOpenContainer {
+        t.Error("Close without container should fail.")
+    }
+    if err := enc.Key("a"); err != ErrUnexpectedKey {
+        t.Error("Key outside of a map should fail.")
+    }
+    enc.OpenMap()
+    if err := enc.Encode(types.NewUInt8(2)); err != ErrMissingKey {
+        t.Error("Map entry without key should fail.")
+    }
+    enc.Key("a")
+    if err := enc.Key("b"); err != ErrKeyWithoutValue {
+        t.Error("Second key without a value should fail.")
+    }
+    if err := enc.Close(); err != ErrKeyWithoutValue {
+        t.Error("Close after a key without a value should fail.")
+    }
+}
+
+func TestDeserializeStreamErrors(t *testing.T) {
+    t.Run("UNTERMINATED", testDeserializeEFunc([]byte{ 7, 0, 3, 4, 2 }, ErrTruncated, 5))
+    t.Run("END", testDeserializeEFunc([]byte{ 8, 0 }, ErrUnexpectedEnd, 0))
+}
+
+func TestEncoderEncode(t *testing.T) {
+    keys := []string{ "NULL", "UINT128", "STRING", "ARRAY", "BINARY", "DATE", "OBJECTID", "INT16_ARRAY" }
+    for _, key := range keys {
+        var buf bytes.Buffer
+        enc := NewEncoder(&buf)
+        enc.Encode(originData[key])
+        if err := enc.Flush(); err != nil || !reflect.DeepEqual(buf.Bytes(), serializedData[key]) {
+            t.Errorf("Encode %s failed: %v", key, err)
+        }
+    }
+
+    nested := types.NewMap(map[string]types.RootType {
+        "list": originData["ARRAY"],
+        "map":  originData["MAP"],
+        "str":  originData["STRING"],
+    })
+    var buf bytes.Buffer
+    enc := NewEncoder(&buf)
+    enc.Encode(nested)
+    enc.Flush()
+    _, value, err := DeserializeE(buf.Bytes(), 0)
+    if err != nil || len(buf.Bytes()) != len(Serialize(nested)) || !types.Equal(value, nested, types.EqualOptions{}) {
+        t.Errorf("Encode nested MAP failed: %v", err)
+    }
+}
+
+func TestEncoderUnsupported(t *testing.T) {
+    values := map[string]types.RootType {
+        "ARRAY":    types.NewSlice([]types.RootType { struct{}{}, types.NewUInt8(1), struct{}{} }),
+        "MAP":      types.NewMap(map[string]types.RootType { "a": struct{}{}, "b": types.NewUInt8(1) }),
+        "NESTED":   types.NewMap(map[string]types.RootType {
+            "list": types.NewSlice([]types.RootType { struct{}{}, types.NewString("s") }),
+            "skip": struct{}{},
+        }),
+    }
+    for name, value := range values {
+        t.Run(name, testEncoderSerializeFunc(value))
+    }
+}
+
+func TestEncoderMarshaler(t *testing.T) {
+    values := map[string]types.RootType {
+        "TOP":      marshalColor { "red" },
+        "ARRAY":    types.NewSlice([]types.RootType { marshalColor { "red" } }),
+        "MAP":      types.NewMap(map[string]types.RootType {
+            "main":     marshalColor { "red" },
+            "accent":   &marshalColor { "blue" },
+        }),
+    }
+    for name, value := range values {
+        t.Run(name, testEncoderSerializeFunc(value))
+    }
+
+    var merr *MarshalerError
+    if err := NewEncoder(&bytes.Buffer{}).Encode(marshalColor{}); !errors.As(err, &merr) {
+        t.Errorf("Encode should wrap the error of MarshalBESON: %v", err)
+    }
+}
+
+// growingMarshaler returns a longer string on every call to MarshalBESON.
+type growingMarshaler struct {
+    calls   *int
+}
+
+func (m growingMarshaler) MarshalBESON() (types.RootType, error) {
+    *m.calls++
+    return types.NewString(strings.Repeat("x", *m.calls)), nil
+}
+
+func TestEncoderMarshalOnce(t *testing.T) {
+    calls := 0
+    value := types.NewSlice([]types.RootType {
+        types.NewSlice([]types.RootType {
+            types.NewSlice([]types.RootType { growingMarshaler { &calls } }),
+        }),
+    })
+    var buf bytes.Buffer
+    enc := NewEncoder(&buf)
+    enc.Encode(value)
+    if err := enc.Flush(); err != nil {
+        t.Fatalf("Encoder marshal once test failed: %v", err)
+    }
+    if _, _, err := DeserializeE(buf.Bytes(), 0); err != nil || calls != 1 {
+        t.Errorf("Encoder marshal once test failed: %d calls, %v", calls, err)
+    } else {
+        t.Log("Encoder marshal once test passed.")
+    }
+}
+
+func testEncoderSerializeFunc(value types.RootType) func(*testing.T) {
+    return func(t *testing.T) {
+        var buf bytes.Buffer
+        enc := NewEncoder(&buf)
+        enc.Encode(value)
+        if err := enc.Flush(); err != nil {
+            t.Fatalf("Encoder serialize test failed: %v", err)
+        }
+        _, _, err := DeserializeE(buf.Bytes(), 0)
+        if err == nil && bytes.Equal(buf.Bytes(), Serialize(value)) {
+            t.Log("Encoder serialize test passed.")
+        } else {
+            t.Errorf("Encoder serialize test failed: % x %v", buf.Bytes(), err)
+        }
+    }
+}
@@ -0,0 +1,140 @@
+package beson
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+
+    "beson/types"
+)
+
+var (
+    ErrTruncated        = errors.New("beson: unexpected end of buffer")
+    ErrUnknownType      = errors.New("beson: unknown type header")
+    ErrLengthOverflow   = errors.New("beson: length prefix exceeds buffer")
+    ErrInvalidLength    = errors.New("beson: length is not a multiple of the element size")
+    ErrUnexpectedEnd    = errors.New("beson: container end marker outside of its container")
+    ErrDuplicateKey     = errors.New("beson: duplicate map key")
+    ErrTrailingData     = errors.New("beson: unexpected data after value")
+    ErrNotContainer     = errors.New("beson: value is not an array or map")
+    ErrKeyNotFound      = errors.New("beson: key not found")
+    ErrPathNotFound     = errors.New("beson: patch path does not exist")
+    ErrInvalidPatch     = errors.New("beson: malformed patch")
+    ErrExtensionHeader  = errors.New("beson: header outside of the extension range")
+    ErrInvalidCodec     = errors.New("beson: codec needs a Go type, Encode and Decode")
+    ErrDuplicateCodec   = errors.New("beson: header or Go type already registered")
+
+    ErrMaxDepth         = errors.New("beson: containers nested too deep")
+    ErrMaxStringLen     = errors.New("beson: string exceeds the length limit")
+    ErrMaxBinaryLen     = errors.New("beson: binary exceeds the length limit")
+    ErrMaxElements      = errors.New("beson: value holds too many elements")
+    ErrMaxTotalBytes    = errors.New("beson: value exceeds the size limit")
+
+    ErrMissingKey       = errors.New("beson: map entry written without a key")
+    ErrKeyWithoutValue  = errors.New("beson: key written without a value")
+    ErrUnexpectedKey    = errors.New("beson: key written outside of a map")
+    ErrNoOpenContainer  = errors.New("beson: no open container to close")
+)
+
+// DeserializeError reports where in the buffer, or for a Decoder in the
+// stream, decoding failed. Err is one of the sentinel errors above, so
+// callers can test it with errors.Is.
+type DeserializeError struct {
+    Offset  int64
+    Err     error
+}
+
+func newDeserializeError(err error, offset uint32) error {
+    return &DeserializeError { Offset: int64(offset), Err: err }
+}
+
+func (e *DeserializeError) Error() string {
+    return fmt.Sprintf("%s at offset %d", e.Err.Error(), e.Offset)
+}
+
+func (e *DeserializeError) Unwrap() error {
+    return e.Err
+}
+
+// UnsupportedTypeError is returned by Marshal for Go values that have no
+// beson representation.
+type UnsupportedTypeError struct {
+    Type    reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+    return "beson: unsupported type: " + e.Type.String()
+}
+
+// UnsupportedValueError is returned by Marshal for Go values whose type is
+// supported but that cannot be encoded, such as a value referring to
+// itself.
+type UnsupportedValueError struct {
+    Type    reflect.Type
+    Msg     string
+}
+
+func (e *UnsupportedValueError) Error() string {
+    return "beson: unsupported value of type " + e.Type.String() + ": " + e.Msg
+}
+
+// MarshalerError wraps the error returned by the MarshalBESON method of a
+// value of type Type.
+type MarshalerError struct {
+    Type    reflect.Type
+    Err     error
+}
+
+func (e *MarshalerError) Error() string {
+    return "beson: error calling MarshalBESON for type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+func (e *MarshalerError) Unwrap() error {
+    return e.Err
+}
+
+// UnmarshalTypeError describes a decoded value that cannot be stored in
+// the Go type it is unmarshaled into.
+type UnmarshalTypeError struct {
+    Value   string
+    Type    reflect.Type
+}
+
+func newUnmarshalTypeError(value types.RootType, t reflect.Type) error {
+    return &UnmarshalTypeError { Value: TypeOf(value), Type: t }
+}
+
+func (e *UnmarshalTypeError) Error() string {
+    return "beson: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+}
+
+// InvalidUnmarshalError is returned when Unmarshal is not given a non-nil
+// pointer.
+type InvalidUnmarshalError struct {
+    Type    reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+    if e.Type == nil {
+        return "beson: Unmarshal(nil)"
+    }
+    if e.Type.Kind() != reflect.Ptr {
+        return "beson: Unmarshal(non-pointer " + e.Type.String() + ")"
+    }
+    return "beson: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// PatchError reports the operation of a patch that could not be applied.
+type PatchError struct {
+    Index   int
+    Path    string
+    Err     error
+}
+
+func (e *PatchError) Error() string {
+    return fmt.Sprintf("%s: operation %d on %q", e.Err.Error(), e.Index, e.Path)
+}
+
+func (e *PatchError) Unwrap() error {
+    return e.Err
+}
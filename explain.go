@@ -0,0 +1,172 @@
+package beson
+
+import (
+    "strconv"
+    "strings"
+)
+
+// Node describes where one value sits in an encoded buffer. Offsets are
+// absolute positions in the buffer given to Explain.
+type Node struct {
+    // Path is the JSON pointer of the value, "" for the top-level one.
+    Path            string
+    // Offset is the position of the type header.
+    Offset          uint32
+    Header          [2]byte
+    Kind            Kind
+    // Keyed is set for map entries, whose key follows the header as a 2
+    // byte length and the key bytes.
+    Keyed           bool
+    Key             string
+    // HasLength is set when the payload is preceded by a 4 byte length
+    // prefix holding Length.
+    HasLength       bool
+    Length          uint32
+    // PayloadStart and PayloadEnd delimit the payload. For delimited
+    // containers it spans the children and the end marker, PayloadEnd is
+    // 0 for those left unfinished by an error.
+    PayloadStart    uint32
+    PayloadEnd      uint32
+}
+
+// Explain walks the single value encoded in data and returns one Node per
+// value, containers before their children. On malformed input it returns
+// the nodes read so far along with a *DeserializeError. Containers nested
+// deeper than DefaultDecodeOptions.MaxDepth are reported as ErrMaxDepth.
+func Explain(data []byte) ([]Node, error) {
+    var nodes []Node
+    end, err := explainValue(data, 0, "", false, 0, &nodes)
+    if err == nil && end != uint32(len(data)) {
+        err = newDeserializeError(ErrTrailingData, end)
+    }
+    return nodes, err
+}
+
+func explainValue(buffer []byte, start uint32, path string, keyed bool, depth int, nodes *[]Node)(uint32, error) {
+    if err := checkBounds(buffer, start, 2); err != nil {
+        return start, err
+    }
+    t := getTypeHeaderKey(buffer[start:start + 2])
+    if t == KindInvalid {
+        return start, newDeserializeError(ErrUnknownType, start)
+    }
+    if t == KindArrayEnd || t == KindMapEnd {
+        return start, newDeserializeError(ErrUnexpectedEnd, start)
+    }
+
+    node := Node {
+        Path:   path,
+        Offset: start,
+        Header: [2]byte{ buffer[start], buffer[start + 1] },
+        Kind:   t,
+    }
+    pos := start + 2
+
+    if keyed {
+        begin, end, err := readLengthPrefix(buffer, pos, 2)
+        if err != nil {
+            return pos, err
+        }
+        node.Keyed = true
+        node.Key = string(buffer[begin:end])
+        node.Path = path + "/" + escapePointer(node.Key)
+        pos = end
+    }
+
+    return explainPayload(buffer, node, pos, depth, nodes)
+}
+
+// explainPayload reads the payload of node, starting at pos, and appends
+// the node once its payload bounds are known, followed by the nodes of its
+// children. depth is the number of containers enclosing the node.
+func explainPayload(buffer []byte, node Node, pos uint32, depth int, nodes *[]Node)(uint32, error) {
+    t := node.Kind
+    node.PayloadStart = pos
+
+    if t == KindArrayStart || t == KindMapStart {
+        // The end of a delimited container is only known once its children
+        // are read, PayloadEnd stays 0 when one of them is invalid.
+        index := len(*nodes)
+        *nodes = append(*nodes, node)
+        if depth >= DefaultDecodeOptions.MaxDepth {
+            return pos, newDeserializeError(ErrMaxDepth, node.Offset)
+        }
+        marker := KindArrayEnd.header()
+        if t == KindMapStart {
+            marker = KindMapEnd.header()
+        }
+
+        for i := 0; ; i++ {
+            if err := checkBounds(buffer, pos, 2); err != nil {
+                return pos, err
+            }
+            if buffer[pos] == marker[0] && buffer[pos + 1] == marker[1] {
+                pos += 2
+                break
+            }
+
+            var err error
+            if t == KindMapStart {
+                pos, err = explainValue(buffer, pos, node.Path, true, depth + 1, nodes)
+            } else {
+                pos, err = explainValue(buffer, pos, node.Path + "/" + strconv.Itoa(i), false, depth + 1, nodes)
+            }
+            if err != nil {
+                return pos, err
+            }
+        }
+        (*nodes)[index].PayloadEnd = pos
+        return pos, nil
+    }
+
+    if t != KindArray && t != KindMap {
+        // Other values are checked by their decoder, so that Explain
+        // accepts exactly what DeserializeE accepts.
+        end, _, err := deserializeData(t, buffer, pos, newDecodeState(depthOnly(), pos))
+        if err != nil {
+            return pos, err
+        }
+        if _, ok := payloadSize[t]; !ok {
+            node.HasLength = true
+            node.PayloadStart = pos + 4
+            node.Length = end - node.PayloadStart
+        }
+        node.PayloadEnd = end
+        *nodes = append(*nodes, node)
+        return end, nil
+    }
+
+    begin, end, err := readLengthPrefix(buffer, pos, 4)
+    if err != nil {
+        return pos, err
+    }
+    node.HasLength = true
+    node.Length = end - begin
+    node.PayloadStart = begin
+    node.PayloadEnd = end
+    *nodes = append(*nodes, node)
+
+    if depth >= DefaultDecodeOptions.MaxDepth {
+        return begin, newDeserializeError(ErrMaxDepth, node.Offset)
+    }
+
+    // Children are read against the container so that one overrunning it
+    // is reported as truncated.
+    container := buffer[:end]
+    for i := 0; begin < end; i++ {
+        if t == KindMap {
+            begin, err = explainValue(container, begin, node.Path, true, depth + 1, nodes)
+        } else {
+            begin, err = explainValue(container, begin, node.Path + "/" + strconv.Itoa(i), false, depth + 1, nodes)
+        }
+        if err != nil {
+            return begin, err
+        }
+    }
+    return end, nil
+}
+
+// escapePointer escapes a map key as a JSON pointer reference token.
+func escapePointer(key string) string {
+    return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
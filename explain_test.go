@@ -0,0 +1,112 @@
+package beson
+
+import (
+    "bytes"
+    "errors"
+    "reflect"
+    "testing"
+
+    "beson/types"
+)
+
+func TestExplain(t *testing.T) {
+    data := Serialize(types.NewMap(map[string]types.RootType {
+        "a/b":  types.NewSlice([]types.RootType { types.NewUInt8(2), nil }),
+        "s":    types.NewString("xy"),
+    }))
+
+    nodes, err := Explain(data)
+    if err != nil {
+        t.Fatalf("Explain failed: %v", err)
+    }
+
+    expect := []Node {
+        { Path: "", Offset: 0, Header: [2]byte{ 0x09, 0x00 }, Kind: KindMap, HasLength: true, Length: 27, PayloadStart: 6, PayloadEnd: 33 },
+        { Path: "/a~1b", Offset: 6, Header: [2]byte{ 0x06, 0x00 }, Kind: KindArray, Keyed: true, Key: "a/b", HasLength: true, Length: 5, PayloadStart: 17, PayloadEnd: 22 },
+        { Path: "/a~1b/0", Offset: 17, Header: [2]byte{ 0x03, 0x04 }, Kind: KindUInt8, PayloadStart: 19, PayloadEnd: 20 },
+        { Path: "/a~1b/1", Offset: 20, Header: [2]byte{ 0x00, 0x00 }, Kind: KindNull, PayloadStart: 22, PayloadEnd: 22 },
+        { Path: "/s", Offset: 22, Header: [2]byte{ 0x05, 0x00 }, Kind: KindString, Keyed: true, Key: "s", HasLength: true, Length: 2, PayloadStart: 31, PayloadEnd: 33 },
+    }
+    if reflect.DeepEqual(nodes, expect) {
+        t.Log("Explain test passed.")
+    } else {
+        t.Errorf("Explain test failed: %+v", nodes)
+    }
+}
+
+func TestExplainCorrupted(t *testing.T) {
+    data := Serialize(types.NewSlice([]types.RootType { types.NewUInt8(2), types.NewUInt8(3) }))
+    data[9] = 0xee
+
+    nodes, err := Explain(data)
+    var derr *DeserializeError
+    if !errors.As(err, &derr) || derr.Err != ErrUnknownType || derr.Offset != 9 {
+        t.Fatalf("Explain should report the unknown header: %v", err)
+    }
+    if len(nodes) != 2 || nodes[1].Path != "/0" {
+        t.Errorf("Explain should return the nodes read so far: %+v", nodes)
+    }
+
+    if _, err := Explain(append(Serialize(nil), 0)); !errors.Is(err, ErrTrailingData) {
+        t.Errorf("Explain should reject trailing data: %v", err)
+    }
+}
+
+func TestExplainInvalid(t *testing.T) {
+    invalid := map[string]struct {
+        data    []byte
+        expect  error
+    } {
+        "INT16_ARRAY":      { []byte{ 0x0f, 0x05, 3, 0, 0, 0, 1, 2, 3 }, ErrInvalidLength },
+        "SPECIAL_BUFFER":   { []byte{ 0x0f, 0xff, 0, 0, 0, 0 }, ErrTruncated },
+        "EXTENSION":        { []byte{ 0xf0, 0x01, 1, 0, 0, 0, 1 }, ErrUnknownType },
+        "NESTED":           { []byte{ 6, 0, 9, 0, 0, 0, 0x0f, 0x05, 3, 0, 0, 0, 1, 2, 3 }, ErrInvalidLength },
+    }
+    for name, c := range invalid {
+        _, _, derr := DeserializeE(c.data, 0)
+        if _, err := Explain(c.data); errors.Is(err, c.expect) && errors.Is(derr, c.expect) {
+            t.Logf("Explain %s test passed.", name)
+        } else {
+            t.Errorf("Explain %s test failed: %v", name, err)
+        }
+    }
+}
+
+func TestExplainTruncated(t *testing.T) {
+    truncated := map[string][]byte {
+        "SCALAR":       { 3, 0, 1 },
+        "STRING":       { 5, 0, 9, 0, 0, 0, 'a', 'b' },
+        "CONTAINER":    { 7, 0, 3, 0 },
+    }
+    for name, data := range truncated {
+        nodes, err := Explain(data)
+        ok := errors.Is(err, ErrTruncated) || errors.Is(err, ErrLengthOverflow)
+        for _, node := range nodes {
+            if node.Kind != KindArrayStart && (node.PayloadEnd < node.PayloadStart || node.PayloadEnd > uint32(len(data))) {
+                ok = false
+            }
+        }
+        if ok {
+            t.Logf("Explain truncated %s test passed.", name)
+        } else {
+            t.Errorf("Explain truncated %s test failed: %+v %v", name, nodes, err)
+        }
+    }
+}
+
+func TestExplainMaxDepth(t *testing.T) {
+    var nested types.RootType = types.NewUInt8(1)
+    for i := 0; i <= DefaultDecodeOptions.MaxDepth; i++ {
+        nested = types.NewSlice([]types.RootType { nested })
+    }
+    delimited := bytes.Repeat([]byte{ 7, 0 }, 1 << 20)
+
+    for name, data := range map[string][]byte { "ARRAY": Serialize(nested), "ARRAY_START": delimited } {
+        nodes, err := Explain(data)
+        if errors.Is(err, ErrMaxDepth) && len(nodes) == DefaultDecodeOptions.MaxDepth + 1 {
+            t.Logf("Explain %s depth test passed.", name)
+        } else {
+            t.Errorf("Explain %s depth test failed: %d nodes, %v", name, len(nodes), err)
+        }
+    }
+}
@@ -172,6 +172,7 @@ func testXorFunc(a []byte, b []byte, expect []byte) func(*testing.T) {
 
 func TestAdd(t *testing.T) {
     t.Run("size4", testAddFunc([]byte{ 204, 19, 240, 255 }, []byte{ 110, 10, 252, 24 }, []byte{ 58, 30, 236, 24 }))
+    t.Run("carry", testAddFunc([]byte{ 255, 255, 0, 0 }, []byte{ 255, 255, 0, 0 }, []byte{ 254, 255, 1, 0 }))
 }
 
 func testAddFunc(a []byte, b []byte, expect []byte) func(*testing.T) {  
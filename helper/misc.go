@@ -240,19 +240,16 @@ func Xor(a []byte, b []byte) {
 }
 
 func Add(a []byte, b []byte) {
-    var carry, nextCarry byte = 0, 0
+    // Sum in 16 bits, comparing against BYTE_MAX - b[i] - carry wraps
+    // around when b[i] is BYTE_MAX and a carry is pending.
+    var carry uint16 = 0
     for i := 0; i < len(a); i++ {
-        if i < len(b) && ((a[i] > BYTE_MAX - b[i] - carry) || (b[i] > BYTE_MAX - a[i] - carry)) {
-            nextCarry = 1
-        } else {
-            nextCarry = 0
-        }
+        sum := uint16(a[i]) + carry
         if i < len(b) {
-            a[i] = a[i] + b[i] + carry
-        } else {
-            a[i] = a[i] + carry
+            sum = sum + uint16(b[i])
         }
-        carry = nextCarry
+        a[i] = byte(sum)
+        carry = sum >> 8
     }
 }
 
@@ -0,0 +1,215 @@
+package beson
+
+import (
+    "encoding/hex"
+
+    "beson/types"
+)
+
+// Kind identifies the type of an encoded value. Its value is the type
+// header read as a big endian number, so that every header, those of the
+// extension range included, has a Kind and Header is free.
+type Kind uint16
+
+const (
+    KindNull            Kind = 0x0000
+    KindFalse           Kind = 0x0100
+    KindTrue            Kind = 0x0101
+
+    KindInt32           Kind = 0x0200
+    KindInt64           Kind = 0x0201
+    KindInt128          Kind = 0x0202
+    KindInt256          Kind = 0x0203
+    KindInt8            Kind = 0x0204
+    KindInt16           Kind = 0x0205
+
+    KindUInt32          Kind = 0x0300
+    KindUInt64          Kind = 0x0301
+    KindUInt128         Kind = 0x0302
+    KindUInt256         Kind = 0x0303
+    KindUInt8           Kind = 0x0304
+    KindUInt16          Kind = 0x0305
+
+    KindFloat64         Kind = 0x0400
+    KindFloat32         Kind = 0x0401
+
+    KindString          Kind = 0x0500
+    KindArray           Kind = 0x0600
+    KindArrayStart      Kind = 0x0700
+    KindArrayEnd        Kind = 0x0800
+    KindMap             Kind = 0x0900
+    KindMapStart        Kind = 0x0a00
+    KindMapEnd          Kind = 0x0b00
+    KindDate            Kind = 0x0c00
+    KindObjectId        Kind = 0x0d00
+    KindBinary          Kind = 0x0e00
+
+    KindArrayBuffer     Kind = 0x0f00
+    KindDataView        Kind = 0x0f01
+    KindUInt8Array      Kind = 0x0f02
+    KindInt8Array       Kind = 0x0f03
+    KindUInt16Array     Kind = 0x0f04
+    KindInt16Array      Kind = 0x0f05
+    KindUInt32Array     Kind = 0x0f06
+    KindInt32Array      Kind = 0x0f07
+    KindFloat32Array    Kind = 0x0f08
+    KindFloat64Array    Kind = 0x0f09
+
+    KindSpecialBuffer   Kind = 0x0fff
+
+    // KindInvalid stands for no type at all, its header is outside of both
+    // the built-in and the extension range.
+    KindInvalid         Kind = 0xffff
+)
+
+// kindNames holds the name of every built-in kind, indexed by header.
+var kindNames [16][256]string
+
+// decodeFunc decodes the payload of kind k starting at start.
+type decodeFunc func(k Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error)
+
+// decoders holds the payload decoder of every built-in kind, indexed by
+// header. It is filled by init as the decoders reach back into it.
+var decoders [16][256]decodeFunc
+
+func init() {
+    for k, name := range map[Kind]string {
+        KindNull: "null", KindFalse: "false", KindTrue: "true",
+        KindInt32: "int32", KindInt64: "int64", KindInt128: "int128",
+        KindInt256: "int256", KindInt8: "int8", KindInt16: "int16",
+        KindUInt32: "uint32", KindUInt64: "uint64", KindUInt128: "uint128",
+        KindUInt256: "uint256", KindUInt8: "uint8", KindUInt16: "uint16",
+        KindFloat64: "float64", KindFloat32: "float32",
+        KindString: "string", KindArray: "array", KindArrayStart: "array_start",
+        KindArrayEnd: "array_end", KindMap: "map", KindMapStart: "map_start",
+        KindMapEnd: "map_end", KindDate: "date", KindObjectId: "objectid",
+        KindBinary: "binary",
+        KindArrayBuffer: "array_buffer", KindDataView: "data_view",
+        KindUInt8Array: "uint8_array", KindInt8Array: "int8_array",
+        KindUInt16Array: "uint16_array", KindInt16Array: "int16_array",
+        KindUInt32Array: "uint32_array", KindInt32Array: "int32_array",
+        KindFloat32Array: "float32_array", KindFloat64Array: "float64_array",
+        KindSpecialBuffer: "special_buffer",
+    } {
+        kindNames[k >> 8][k & 0xff] = name
+    }
+
+    fixed := func(decode func([]byte, uint32)(uint32, types.RootType, error)) decodeFunc {
+        return func(k Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+            return decode(buffer, start)
+        }
+    }
+    limited := func(decode func([]byte, uint32, *decodeState)(uint32, types.RootType, error)) decodeFunc {
+        return func(k Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+            return decode(buffer, start, st)
+        }
+    }
+    boolean := func(k Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+        return deserializeBoolean(k, start)
+    }
+    end := func(k Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+        return start, nil, newDeserializeError(ErrUnexpectedEnd, start - 2)
+    }
+    typedArray := func(k Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+        return deserializeTypedArray(k, buffer, start, st)
+    }
+
+    for k, decode := range map[Kind]decodeFunc {
+        KindNull:           fixed(func(buffer []byte, start uint32)(uint32, types.RootType, error) {
+            return deserializeNull(start)
+        }),
+        KindFalse:          boolean,
+        KindTrue:           boolean,
+        KindInt8:           fixed(deserializeInt8),
+        KindInt16:          fixed(deserializeInt16),
+        KindInt32:          fixed(deserializeInt32),
+        KindInt64:          fixed(deserializeInt64),
+        KindInt128:         fixed(deserializeInt128),
+        KindInt256:         fixed(deserializeInt256),
+        KindUInt8:          fixed(deserializeUInt8),
+        KindUInt16:         fixed(deserializeUInt16),
+        KindUInt32:         fixed(deserializeUInt32),
+        KindUInt64:         fixed(deserializeUInt64),
+        KindUInt128:        fixed(deserializeUInt128),
+        KindUInt256:        fixed(deserializeUInt256),
+        KindFloat32:        fixed(deserializeFloat32),
+        KindFloat64:        fixed(deserializeFloat64),
+        KindString:         limited(deserializeString),
+        KindArray:          limited(deserializeSlice),
+        KindMap:            limited(deserializeMap),
+        KindBinary:         limited(deserializeBinary),
+        KindArrayStart:     limited(deserializeSliceStream),
+        KindMapStart:       limited(deserializeMapStream),
+        KindArrayEnd:       end,
+        KindMapEnd:         end,
+        KindDate:           fixed(deserializeDate),
+        KindObjectId:       fixed(deserializeObjectId),
+        KindArrayBuffer:    typedArray,
+        KindDataView:       typedArray,
+        KindUInt8Array:     typedArray,
+        KindInt8Array:      typedArray,
+        KindUInt16Array:    typedArray,
+        KindInt16Array:     typedArray,
+        KindUInt32Array:    typedArray,
+        KindInt32Array:     typedArray,
+        KindFloat32Array:   typedArray,
+        KindFloat64Array:   typedArray,
+        KindSpecialBuffer:  limited(deserializeSpecialBuffer),
+    } {
+        decoders[k >> 8][k & 0xff] = decode
+    }
+}
+
+// KindOfHeader returns the kind of a type header, KindInvalid when the
+// header is unknown.
+func KindOfHeader(header [2]byte) Kind {
+    return kindOfHeader(header[:])
+}
+
+func kindOfHeader(header []byte) Kind {
+    if isExtensionHeader(header) {
+        return Kind(header[0]) << 8 | Kind(header[1])
+    }
+    if header[0] < 16 && kindNames[header[0]][header[1]] != "" {
+        return Kind(header[0]) << 8 | Kind(header[1])
+    }
+    return KindInvalid
+}
+
+// KindOf returns the kind data is serialized as by Serialize, KindInvalid
+// when it is not a supported value.
+func KindOf(data interface{}) Kind {
+    return getType(data)
+}
+
+// Header returns the type header written for k.
+func (k Kind) Header() [2]byte {
+    return [2]byte{ byte(k >> 8), byte(k) }
+}
+
+// String returns the DATA_TYPE value of k, e.g. "uint8", or for the
+// extension range a name holding its header, e.g. "extension_f001".
+func (k Kind) String() string {
+    header := k.Header()
+    if k.IsExtension() {
+        return extensionPrefix + hex.EncodeToString(header[:])
+    }
+    if header[0] < 16 && kindNames[header[0]][header[1]] != "" {
+        return kindNames[header[0]][header[1]]
+    }
+    return "invalid"
+}
+
+// IsExtension reports whether k lies in the extension range, see Registry.
+func (k Kind) IsExtension() bool {
+    header := k.Header()
+    return isExtensionHeader(header[:])
+}
+
+// header returns the type header of k as a slice, empty for KindInvalid.
+func (k Kind) header() []byte {
+    if k == KindInvalid {
+        return []byte{}
+    }
+    return []byte{ byte(k >> 8), byte(k) }
+}
@@ -0,0 +1,43 @@
+package beson
+
+import (
+    "strings"
+    "testing"
+
+    "beson/types"
+)
+
+func TestKind(t *testing.T) {
+    t.Run("UINT8", testKindFunc(types.NewUInt8(2), KindUInt8, [2]byte{ 0x03, 0x04 }, "uint8"))
+    t.Run("TRUE", testKindFunc(types.NewBool(true), KindTrue, [2]byte{ 0x01, 0x01 }, "true"))
+    t.Run("SPECIAL_BUFFER", testKindFunc(types.NewSpecialBuffer(0, nil), KindSpecialBuffer, [2]byte{ 0x0f, 0xff }, "special_buffer"))
+    t.Run("INVALID", testKindFunc(make(chan int), KindInvalid, [2]byte{ 0xff, 0xff }, "invalid"))
+
+    if k := KindOfHeader([2]byte{ 0xf0, 0x01 }); !k.IsExtension() || k.String() != "extension_f001" {
+        t.Errorf("Extension header should have a kind: %v", k)
+    }
+    if k := KindOfHeader([2]byte{ 0x7f, 0x7f }); k != KindInvalid {
+        t.Errorf("Unknown header should be invalid: %v", k)
+    }
+}
+
+func testKindFunc(value types.RootType, expect Kind, header [2]byte, name string) func(*testing.T) {
+    return func(t *testing.T) {
+        k := KindOf(value)
+        if k == expect && k.Header() == header && k.String() == name {
+            t.Log("Kind test passed.")
+        } else {
+            t.Errorf("Kind test failed: %v", k)
+        }
+    }
+}
+
+// The deprecated maps must keep agreeing with the kinds.
+func TestKindAliases(t *testing.T) {
+    for key, header := range TYPE_HEADER {
+        k := KindOfHeader([2]byte{ header[0], header[1] })
+        if k.String() != DATA_TYPE[key] || strings.ToUpper(k.String()) != key {
+            t.Errorf("Kind of %s disagrees with DATA_TYPE: %v", key, k)
+        }
+    }
+}
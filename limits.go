@@ -0,0 +1,114 @@
+package beson
+
+// DecodeOptions bounds the resources a single decoded value may claim, so
+// that hostile input fails fast instead of exhausting memory or stack. A
+// zero field means no limit.
+type DecodeOptions struct {
+    // MaxDepth is the deepest container nesting allowed.
+    MaxDepth        int
+    // MaxStringLen is the largest STRING payload in bytes.
+    MaxStringLen    uint32
+    // MaxBinaryLen is the largest BINARY, SPECIAL_BUFFER, typed array or
+    // extension payload in bytes.
+    MaxBinaryLen    uint32
+    // MaxElements is the largest number of values, containers and their
+    // children included, a decoded value may hold.
+    MaxElements     int
+    // MaxTotalBytes is the largest encoded size of a value. It is checked
+    // against every length prefix, so an oversized container is rejected
+    // before its children are decoded.
+    MaxTotalBytes   uint32
+}
+
+// DefaultDecodeOptions are the limits used when Options.Limits is nil.
+// They suit values received from the network. Deserialize, DeserializeE
+// and Unmarshal only apply MaxDepth.
+var DefaultDecodeOptions = DecodeOptions {
+    MaxDepth:       256,
+    MaxStringLen:   16 << 20,
+    MaxBinaryLen:   16 << 20,
+    MaxElements:    1 << 20,
+    MaxTotalBytes:  64 << 20,
+}
+
+func (opts Options) limits() DecodeOptions {
+    if opts.Limits == nil {
+        return DefaultDecodeOptions
+    }
+    return *opts.Limits
+}
+
+// decodeState carries the options of one decoding call along with the
+// counters its limits are checked against.
+type decodeState struct {
+    opts        Options
+    limits      DecodeOptions
+    origin      uint32
+    depth       int
+    elements    int
+}
+
+// newDecodeState starts decoding a value whose encoding begins at origin.
+func newDecodeState(opts Options, origin uint32) *decodeState {
+    return &decodeState { opts: opts, limits: opts.limits(), origin: origin }
+}
+
+// element accounts for a value whose header sits at offset.
+func (st *decodeState) element(offset uint32) error {
+    st.elements++
+    if st.limits.MaxElements > 0 && st.elements > st.limits.MaxElements {
+        return newDeserializeError(ErrMaxElements, offset)
+    }
+    return nil
+}
+
+// enter accounts for a container whose header sits at offset, leave must
+// be called once its children are decoded.
+func (st *decodeState) enter(offset uint32) error {
+    st.depth++
+    if st.limits.MaxDepth > 0 && st.depth > st.limits.MaxDepth {
+        return newDeserializeError(ErrMaxDepth, offset)
+    }
+    return nil
+}
+
+func (st *decodeState) leave() {
+    st.depth--
+}
+
+// checkTotal rejects a value whose encoding reaches at least end.
+func (st *decodeState) checkTotal(end uint32) error {
+    if max := st.limits.MaxTotalBytes; max > 0 && end - st.origin > max {
+        return newDeserializeError(ErrMaxTotalBytes, st.origin + max)
+    }
+    return nil
+}
+
+// readLimitedLength is readLengthPrefix for the 4 byte length of a value
+// of type t, also rejecting lengths above the limit of that type and
+// values reaching past MaxTotalBytes.
+func (st *decodeState) readLimitedLength(t Kind, buffer []byte, start uint32)(uint32, uint32, error) {
+    begin, end, err := readLengthPrefix(buffer, start, 4)
+    if err != nil {
+        return begin, end, err
+    }
+    if max, limitErr := lengthLimit(t, st.limits); max > 0 && end - begin > max {
+        return start, start, newDeserializeError(limitErr, start)
+    }
+    if err := st.checkTotal(end); err != nil {
+        return start, start, err
+    }
+    return begin, end, nil
+}
+
+// lengthLimit returns the largest payload allowed for type t and the error
+// reported beyond it, or 0 when the type has no length limit.
+func lengthLimit(t Kind, limits DecodeOptions) (uint32, error) {
+    if t == KindString {
+        return limits.MaxStringLen, ErrMaxStringLen
+    }
+    if _, ok := typedArrayElementSize[t]; ok || t == KindBinary || t == KindSpecialBuffer || t.IsExtension() {
+        return limits.MaxBinaryLen, ErrMaxBinaryLen
+    }
+    return 0, nil
+}
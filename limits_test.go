@@ -0,0 +1,100 @@
+package beson
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "testing"
+
+    "beson/types"
+)
+
+func nestedSlice(depth int) types.RootType {
+    var value types.RootType = types.NewSlice([]types.RootType{})
+    for i := 1; i < depth; i++ {
+        value = types.NewSlice([]types.RootType { value })
+    }
+    return value
+}
+
+func TestDecodeLimits(t *testing.T) {
+    limits := DecodeOptions { MaxDepth: 3, MaxStringLen: 4, MaxBinaryLen: 2, MaxElements: 4, MaxTotalBytes: 16 }
+
+    t.Run("DEPTH", testDecodeLimitFunc(Serialize(nestedSlice(4)), DecodeOptions { MaxDepth: 3 }, ErrMaxDepth, 18))
+    t.Run("STREAM_DEPTH", testDecodeLimitFunc([]byte{ 7, 0, 7, 0, 7, 0, 7, 0, 8, 0, 8, 0, 8, 0, 8, 0 }, limits, ErrMaxDepth, 6))
+    t.Run("STRING", testDecodeLimitFunc(serializedData["STRING"], limits, ErrMaxStringLen, 2))
+    t.Run("BINARY", testDecodeLimitFunc(serializedData["INT16_ARRAY"], DecodeOptions { MaxBinaryLen: 2 }, ErrMaxBinaryLen, 2))
+    t.Run("ELEMENTS", testDecodeLimitFunc(Serialize(types.NewSlice([]types.RootType { nil, nil, nil, nil })), limits, ErrMaxElements, 12))
+    t.Run("TOTAL", testDecodeLimitFunc(serializedData["UINT128"], DecodeOptions { MaxTotalBytes: 16 }, ErrMaxTotalBytes, 16))
+
+    // The container is rejected on its length, before the corrupted last
+    // element is reached.
+    elements := make([]types.RootType, 10)
+    for i := range elements {
+        elements[i] = types.NewUInt8(uint8(i))
+    }
+    corrupted := Serialize(types.NewSlice(elements))
+    corrupted[len(corrupted) - 3] = 0xee
+    t.Run("TOTAL_CONTAINER", testDecodeLimitFunc(corrupted, DecodeOptions { MaxTotalBytes: 16 }, ErrMaxTotalBytes, 16))
+
+    if _, _, err := DeserializeWithOptions(Serialize(nestedSlice(300)), 0, Options{}); !errors.Is(err, ErrMaxDepth) {
+        t.Errorf("Default limits should reject deep nesting: %v", err)
+    }
+    if _, _, err := DeserializeWithOptions(Serialize(nestedSlice(300)), 0, Options { Limits: &DecodeOptions{} }); err != nil {
+        t.Errorf("Zero limits should disable every check: %v", err)
+    }
+
+    // Deserialize and DeserializeE predate the limits and apply none.
+    large := Serialize(types.NewBinary(0).(*types.Binary).FromBytes(make([]byte, DefaultDecodeOptions.MaxBinaryLen + 1)))
+    if _, value := Deserialize(large, 0); value == nil {
+        t.Error("Deserialize should not apply the default limits.")
+    }
+
+    // Nesting stays bounded, deep input must not overflow the stack.
+    const depth = 1 << 20
+    nested := make([]byte, 6 * depth)
+    for i := 0; i < depth; i++ {
+        nested[6 * i] = 6
+        binary.LittleEndian.PutUint32(nested[6 * i + 2:], uint32(6 * (depth - i - 1)))
+    }
+    delimited := bytes.Repeat([]byte{ 7, 0 }, depth)
+    for name, data := range map[string][]byte { "ARRAY": nested, "ARRAY_START": delimited } {
+        if _, _, err := DeserializeE(data, 0); !errors.Is(err, ErrMaxDepth) {
+            t.Errorf("DeserializeE should bound %s nesting: %v", name, err)
+        }
+        if _, value := Deserialize(data, 0); value != nil {
+            t.Errorf("Deserialize should bound %s nesting.", name)
+        }
+    }
+}
+
+func testDecodeLimitFunc(ser []byte, limits DecodeOptions, expect error, offset uint32) func(*testing.T) {
+    return func(t *testing.T) {
+        _, _, err := DeserializeWithOptions(ser, 0, Options { Limits: &limits })
+        var derr *DeserializeError
+        if errors.As(err, &derr) && errors.Is(err, expect) && derr.Offset == int64(offset) {
+            t.Log("Decode limit test passed.")
+        } else {
+            t.Errorf("Decode limit test failed: %v", err)
+        }
+    }
+}
+
+func TestDecoderLimits(t *testing.T) {
+    // A STRING claiming 4 GB must be rejected before its payload is read.
+    dec := NewDecoder(bytes.NewReader([]byte{ 5, 0, 255, 255, 255, 255 }))
+    if _, err := dec.Decode(); !errors.Is(err, ErrMaxStringLen) {
+        t.Errorf("Decoder should enforce the string length limit: %v", err)
+    }
+
+    stream := append(bytes.Repeat([]byte{ 7, 0 }, 300), bytes.Repeat([]byte{ 8, 0 }, 300)...)
+    dec = NewDecoder(bytes.NewReader(stream))
+    if _, err := dec.Decode(); !errors.Is(err, ErrMaxDepth) {
+        t.Errorf("Decoder should enforce the depth limit: %v", err)
+    }
+
+    dec = NewDecoderWithOptions(bytes.NewReader(serializedData["STRING"]), Options { Limits: &DecodeOptions { MaxTotalBytes: 8 } })
+    if _, err := dec.Decode(); !errors.Is(err, ErrMaxTotalBytes) {
+        t.Errorf("Decoder should enforce the size limit: %v", err)
+    }
+}
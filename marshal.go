@@ -0,0 +1,433 @@
+package beson
+
+import (
+    "reflect"
+    "strings"
+    "time"
+
+    "beson/types"
+)
+
+var typesPkgPath = reflect.TypeOf(types.String{}).PkgPath()
+var timeType = reflect.TypeOf(time.Time{})
+var marshalerType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+
+// Marshaler is implemented by types that build their own beson value. It
+// is honored by Marshal, ToRootType and Serialize, the latter writing
+// nothing for a value whose MarshalBESON fails.
+type Marshaler interface {
+    MarshalBESON() (types.RootType, error)
+}
+
+// Marshal returns the beson encoding of v. Go values are mapped onto the
+// wrapper types of the types package (int32 to INT32, []byte to BINARY,
+// []float32 and the other fixed width number slices to typed arrays,
+// time.Time to DATE, map[string]T and structs to MAP, ...) before being
+// serialized. Struct fields are named after the `beson:"name,omitempty"`
+// tag when present. Values implementing Marshaler are replaced by the
+// value their MarshalBESON returns, values of a type bound in
+// DefaultRegistry are written through its codec.
+func Marshal(v interface{}) ([]byte, error) {
+    return MarshalWithOptions(v, Options{})
+}
+
+// MarshalWithOptions is Marshal with encoding options, types bound in
+// opts.Registry are written through their codec.
+func MarshalWithOptions(v interface{}, opts Options) ([]byte, error) {
+    root, err := ToRootTypeWithOptions(v, opts)
+    if err != nil {
+        return nil, err
+    }
+    return SerializeWithOptions(root, opts), nil
+}
+
+// ToRootType converts a Go value into the types tree Marshal serializes.
+func ToRootType(v interface{}) (types.RootType, error) {
+    return ToRootTypeWithOptions(v, Options{})
+}
+
+// ToRootTypeWithOptions is ToRootType resolving extension types through
+// opts.Registry.
+func ToRootTypeWithOptions(v interface{}, opts Options) (types.RootType, error) {
+    if v == nil {
+        return nil, nil
+    }
+    return marshalValue(reflect.ValueOf(v), newMarshalState(opts.registry()))
+}
+
+// marshalState tracks the pointers, maps and slices being marshaled, so
+// that a value referring to itself is reported instead of recursing
+// forever, along with the registry values are looked up in.
+type marshalState struct {
+    reg     *Registry
+    seen    map[reference]bool
+}
+
+type reference struct {
+    t       reflect.Type
+    ptr     uintptr
+    len     int
+}
+
+func newMarshalState(reg *Registry) *marshalState {
+    return &marshalState { reg: reg, seen: map[reference]bool{} }
+}
+
+// enter marks the pointer, map or slice v as being marshaled, leave must
+// be called once its elements are done.
+func (st *marshalState) enter(v reflect.Value) (reference, error) {
+    ref := reference { t: v.Type(), ptr: v.Pointer() }
+    if v.Kind() == reflect.Slice {
+        ref.len = v.Len()
+    }
+    if st.seen[ref] {
+        return ref, &UnsupportedValueError { Type: v.Type(), Msg: "value refers to itself" }
+    }
+    st.seen[ref] = true
+    return ref, nil
+}
+
+func (st *marshalState) leave(ref reference) {
+    delete(st.seen, ref)
+}
+
+func marshalValue(v reflect.Value, st *marshalState) (types.RootType, error) {
+    if !v.IsValid() {
+        return nil, nil
+    }
+
+    t := v.Type()
+    if t.Kind() != reflect.Interface && t.Implements(marshalerType) {
+        if t.Kind() == reflect.Ptr && v.IsNil() {
+            return nil, nil
+        }
+        return callMarshaler(v)
+    }
+    if t.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(t).Implements(marshalerType) {
+        return callMarshaler(v.Addr())
+    }
+    if v.CanInterface() && st.reg.lookupType(v.Interface()) != nil {
+        return v.Interface(), nil
+    }
+    if isWrapperType(t) {
+        return marshalWrapper(v)
+    }
+    if t == timeType {
+        return types.NewDate(v.Interface().(time.Time)), nil
+    }
+
+    switch v.Kind() {
+    case reflect.Interface:
+        if v.IsNil() {
+            return nil, nil
+        }
+        return marshalValue(v.Elem(), st)
+    case reflect.Ptr:
+        if v.IsNil() {
+            return nil, nil
+        }
+        ref, err := st.enter(v)
+        if err != nil {
+            return nil, err
+        }
+        defer st.leave(ref)
+        return marshalValue(v.Elem(), st)
+    case reflect.Bool:
+        return types.NewBool(v.Bool()), nil
+    case reflect.Int8:
+        return types.NewInt8(int8(v.Int())), nil
+    case reflect.Int16:
+        return types.NewInt16(int16(v.Int())), nil
+    case reflect.Int32:
+        return types.NewInt32(int32(v.Int())), nil
+    case reflect.Int64, reflect.Int:
+        return types.NewInt64(v.Int()), nil
+    case reflect.Uint8:
+        return types.NewUInt8(uint8(v.Uint())), nil
+    case reflect.Uint16:
+        return types.NewUInt16(uint16(v.Uint())), nil
+    case reflect.Uint32:
+        return types.NewUInt32(uint32(v.Uint())), nil
+    case reflect.Uint64, reflect.Uint, reflect.Uintptr:
+        return types.NewUInt64(v.Uint()), nil
+    case reflect.Float32:
+        return types.NewFloat32(float32(v.Float())), nil
+    case reflect.Float64:
+        return types.NewFloat64(v.Float()), nil
+    case reflect.String:
+        return types.NewString(v.String()), nil
+    case reflect.Slice:
+        if v.IsNil() {
+            return nil, nil
+        }
+        if t.Elem().Kind() == reflect.Uint8 {
+            bs := make([]byte, v.Len())
+            copy(bs, v.Bytes())
+            return types.NewBinary(0).(*types.Binary).FromBytes(bs), nil
+        }
+        if array := marshalTypedArray(v); array != nil {
+            return array, nil
+        }
+        ref, err := st.enter(v)
+        if err != nil {
+            return nil, err
+        }
+        defer st.leave(ref)
+        return marshalSlice(v, st)
+    case reflect.Array:
+        return marshalSlice(v, st)
+    case reflect.Map:
+        if v.IsNil() {
+            return nil, nil
+        }
+        ref, err := st.enter(v)
+        if err != nil {
+            return nil, err
+        }
+        defer st.leave(ref)
+        return marshalMap(v, st)
+    case reflect.Struct:
+        return marshalStruct(v, st)
+    }
+
+    return nil, &UnsupportedTypeError { Type: t }
+}
+
+func callMarshaler(v reflect.Value) (types.RootType, error) {
+    root, err := v.Interface().(Marshaler).MarshalBESON()
+    if err != nil {
+        return nil, &MarshalerError { Type: v.Type(), Err: err }
+    }
+    return root, nil
+}
+
+// marshaled returns the value Serialize writes in place of data, the
+// result of MarshalBESON for a Marshaler and data itself otherwise.
+func marshaled(data interface{}) interface{} {
+    m, ok := data.(Marshaler)
+    if !ok || builtinType(data) != KindInvalid {
+        return data
+    }
+    if v := reflect.ValueOf(data); v.Kind() == reflect.Ptr && v.IsNil() {
+        return nil
+    }
+    root, err := m.MarshalBESON()
+    if err != nil {
+        return data
+    }
+    return root
+}
+
+func isWrapperType(t reflect.Type) bool {
+    if t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+    return t.Kind() == reflect.Struct && t.PkgPath() == typesPkgPath
+}
+
+func marshalWrapper(v reflect.Value) (types.RootType, error) {
+    if v.Kind() == reflect.Ptr {
+        if v.IsNil() {
+            return nil, nil
+        }
+    } else {
+        // Wrappers are only handled through pointers, copy values held
+        // by value into a fresh one.
+        ptr := reflect.New(v.Type())
+        ptr.Elem().Set(v)
+        v = ptr
+    }
+
+    root := v.Interface()
+    if getType(root) == KindInvalid {
+        return nil, &UnsupportedTypeError { Type: v.Type() }
+    }
+    return root, nil
+}
+
+func marshalSlice(v reflect.Value, st *marshalState) (types.RootType, error) {
+    slice := make([]types.RootType, v.Len())
+    for i := 0; i < v.Len(); i++ {
+        element, err := marshalValue(v.Index(i), st)
+        if err != nil {
+            return nil, err
+        }
+        slice[i] = element
+    }
+    return types.NewSlice(slice), nil
+}
+
+// marshalTypedArray copies a slice of fixed width numbers into the typed
+// array of its element type, nil for any other slice. Named element types
+// are left to marshalSlice, they may implement Marshaler and the typed
+// array would not convert back into them.
+func marshalTypedArray(v reflect.Value) types.RootType {
+    elem := v.Type().Elem()
+    if elem.PkgPath() != "" {
+        return nil
+    }
+    switch elem.Kind() {
+    case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint16, reflect.Uint32, reflect.Float32, reflect.Float64:
+    default:
+        return nil
+    }
+
+    array := reflect.MakeSlice(reflect.SliceOf(elem), v.Len(), v.Len())
+    reflect.Copy(array, v)
+    switch a := array.Interface().(type) {
+    case []int8:
+        return types.NewInt8Array(a)
+    case []int16:
+        return types.NewInt16Array(a)
+    case []int32:
+        return types.NewInt32Array(a)
+    case []uint16:
+        return types.NewUInt16Array(a)
+    case []uint32:
+        return types.NewUInt32Array(a)
+    case []float32:
+        return types.NewFloat32Array(a)
+    case []float64:
+        return types.NewFloat64Array(a)
+    }
+    return nil
+}
+
+func marshalMap(v reflect.Value, st *marshalState) (types.RootType, error) {
+    if v.Type().Key().Kind() != reflect.String {
+        return nil, &UnsupportedTypeError { Type: v.Type() }
+    }
+
+    m := make(map[string]types.RootType, v.Len())
+    iter := v.MapRange()
+    for iter.Next() {
+        element, err := marshalValue(iter.Value(), st)
+        if err != nil {
+            return nil, err
+        }
+        m[iter.Key().String()] = element
+    }
+    return types.NewMap(m), nil
+}
+
+func marshalStruct(v reflect.Value, st *marshalState) (types.RootType, error) {
+    m := map[string]types.RootType{}
+    for _, f := range structFields(v.Type()) {
+        fv, ok := fieldByIndex(v, f.index)
+        if !ok {
+            continue
+        }
+        if f.omitEmpty && isEmptyValue(fv) {
+            continue
+        }
+        element, err := marshalValue(fv, st)
+        if err != nil {
+            return nil, err
+        }
+        m[f.name] = element
+    }
+    return types.NewMap(m), nil
+}
+
+type field struct {
+    name        string
+    index       []int
+    omitEmpty   bool
+}
+
+// structFields lists the encodable fields of t. Exported fields are named
+// by their beson tag or Go name, untagged embedded structs are inlined and
+// fields tagged "-" are skipped. A struct embedding itself, directly or
+// not, is only inlined once.
+func structFields(t reflect.Type) []field {
+    return embeddedFields(t, map[reflect.Type]bool { t: true })
+}
+
+// embeddedFields is structFields for t, embedded in the structs of visited.
+func embeddedFields(t reflect.Type, visited map[reflect.Type]bool) []field {
+    var fields []field
+    for i := 0; i < t.NumField(); i++ {
+        sf := t.Field(i)
+        tag := sf.Tag.Get("beson")
+        if tag == "-" {
+            continue
+        }
+
+        name, options := tag, ""
+        if idx := strings.Index(tag, ","); idx >= 0 {
+            name, options = tag[:idx], tag[idx + 1:]
+        }
+
+        ft := sf.Type
+        if ft.Kind() == reflect.Ptr {
+            ft = ft.Elem()
+        }
+        if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct && !isWrapperType(ft) && ft != timeType {
+            if visited[ft] {
+                continue
+            }
+            visited[ft] = true
+            for _, inner := range embeddedFields(ft, visited) {
+                inner.index = append([]int{ i }, inner.index...)
+                fields = append(fields, inner)
+            }
+            delete(visited, ft)
+            continue
+        }
+        if sf.PkgPath != "" {
+            continue
+        }
+
+        if name == "" {
+            name = sf.Name
+        }
+        fields = append(fields, field {
+            name:       name,
+            index:      []int{ i },
+            omitEmpty:  hasOption(options, "omitempty"),
+        })
+    }
+    return fields
+}
+
+func hasOption(options string, option string) bool {
+    for _, o := range strings.Split(options, ",") {
+        if o == option {
+            return true
+        }
+    }
+    return false
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex without the panic on nil
+// embedded pointers.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+    for i, x := range index {
+        if i > 0 && v.Kind() == reflect.Ptr {
+            if v.IsNil() {
+                return reflect.Value{}, false
+            }
+            v = v.Elem()
+        }
+        v = v.Field(x)
+    }
+    return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+    switch v.Kind() {
+    case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+        return v.Len() == 0
+    case reflect.Bool:
+        return !v.Bool()
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return v.Int() == 0
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        return v.Uint() == 0
+    case reflect.Float32, reflect.Float64:
+        return v.Float() == 0
+    case reflect.Interface, reflect.Ptr:
+        return v.IsNil()
+    }
+    return false
+}
@@ -0,0 +1,255 @@
+package beson
+
+import (
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "beson/types"
+)
+
+type marshalInner struct {
+    Zip     string  `beson:"zip"`
+}
+
+type marshalEmbedded struct {
+    Region  string
+}
+
+type marshalRecord struct {
+    marshalEmbedded
+    Name    string              `beson:"name"`
+    Age     int32               `beson:"age"`
+    Score   float64             `beson:"score,omitempty"`
+    Tags    []string            `beson:"tags"`
+    Raw     []byte              `beson:"raw"`
+    Address *marshalInner       `beson:"address"`
+    Extra   map[string]uint16   `beson:"extra"`
+    Big     *types.UInt128      `beson:"big"`
+    Created time.Time           `beson:"created"`
+    Skip    string              `beson:"-"`
+    hidden  string
+}
+
+// marshalColor is written as its name rather than its fields.
+type marshalColor struct {
+    name    string
+}
+
+func (c marshalColor) MarshalBESON() (types.RootType, error) {
+    if c.name == "" {
+        return nil, errors.New("unnamed color")
+    }
+    return types.NewString(strings.ToUpper(c.name)), nil
+}
+
+func (c *marshalColor) UnmarshalBESON(root types.RootType) error {
+    s, ok := root.(*types.String)
+    if !ok {
+        return errors.New("color is not a string")
+    }
+    c.name = strings.ToLower(s.Get())
+    return nil
+}
+
+type marshalPalette struct {
+    Main    marshalColor    `beson:"main"`
+    Accent  *marshalColor   `beson:"accent"`
+}
+
+func TestMarshal(t *testing.T) {
+    t.Run("INT32", testMarshalFunc(int32(-3), serializedData["INT32"]))
+    t.Run("UINT8", testMarshalFunc(uint8(2), serializedData["UINT8"]))
+    t.Run("FLOAT32", testMarshalFunc(float32(0.456), serializedData["FLOAT32"]))
+    t.Run("STRING", testMarshalFunc("Hello world", serializedData["STRING"]))
+    t.Run("NULL", testMarshalFunc(nil, serializedData["NULL"]))
+    t.Run("ARRAY", testMarshalFunc([]interface{}{ float32(0.456), int32(-3) }, serializedData["ARRAY"]))
+    t.Run("BINARY", testMarshalFunc([]byte{ 2, 86, 72, 119 }, serializedData["BINARY"]))
+    t.Run("DATE", testMarshalFunc(time.UnixMilli(1554249600123), serializedData["DATE"]))
+    t.Run("WRAPPER", testMarshalFunc(originData["UINT128"], serializedData["UINT128"]))
+}
+
+func testMarshalFunc(data interface{}, expect []byte) func(*testing.T) {
+    return func(t *testing.T) {
+        actual, err := Marshal(data)
+        if err == nil && reflect.DeepEqual(actual, expect) {
+            t.Log("Marshal test passed.")
+        } else {
+            t.Errorf("Marshal test failed: %v", err)
+        }
+    }
+}
+
+func TestMarshalTypedArray(t *testing.T) {
+    t.Run("INT16_ARRAY", testMarshalFunc([]int16{ 1, -2 }, Serialize(types.NewInt16Array([]int16{ 1, -2 }))))
+    t.Run("FLOAT32_ARRAY", testMarshalFunc([]float32{ 0.5 }, Serialize(types.NewFloat32Array([]float32{ 0.5 }))))
+    t.Run("INT64", testMarshalFunc([]int64{ 1 }, Serialize(types.NewSlice([]types.RootType { types.NewInt64(1) }))))
+
+    type samples struct {
+        Values  []uint32        `beson:"values"`
+        Colors  []marshalColor  `beson:"colors"`
+    }
+    origin := samples { Values: []uint32{ 7, 8 }, Colors: []marshalColor { { "red" } } }
+    ser, err := Marshal(origin)
+    if err != nil {
+        t.Fatalf("Marshal failed: %v", err)
+    }
+    var actual samples
+    if err := Unmarshal(ser, &actual); err != nil || !reflect.DeepEqual(actual, origin) {
+        t.Errorf("Typed arrays should round trip: %+v %v", actual, err)
+    }
+}
+
+// marshalNode embeds a pointer to itself.
+type marshalNode struct {
+    *marshalNode
+    X       int8
+    Next    *marshalNode    `beson:"next"`
+}
+
+func TestMarshalRecursive(t *testing.T) {
+    expect := Serialize(types.NewMap(map[string]types.RootType { "X": types.NewInt8(1), "next": nil }))
+    t.Run("EMBEDDED", testMarshalFunc(marshalNode { X: 1 }, expect))
+
+    cyclic := &marshalNode { X: 1 }
+    cyclic.Next = cyclic
+    var verr *UnsupportedValueError
+    if _, err := Marshal(cyclic); !errors.As(err, &verr) {
+        t.Errorf("Marshal should reject a cyclic pointer: %v", err)
+    }
+    m := map[string]interface{}{}
+    m["self"] = m
+    if _, err := Marshal(m); !errors.As(err, &verr) {
+        t.Errorf("Marshal should reject a cyclic map: %v", err)
+    }
+
+    // The same pointer twice is not a cycle.
+    shared := &marshalNode { X: 2 }
+    if _, err := Marshal([]*marshalNode { shared, shared }); err != nil {
+        t.Errorf("Marshal should accept shared pointers: %v", err)
+    }
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+    if _, err := Marshal(make(chan int)); err == nil {
+        t.Error("Marshal should reject channels.")
+    }
+    if _, err := Marshal(map[int]string{ 1: "a" }); err == nil {
+        t.Error("Marshal should reject non-string map keys.")
+    }
+}
+
+func TestMarshalStruct(t *testing.T) {
+    origin := marshalRecord {
+        marshalEmbedded: marshalEmbedded { Region: "eu" },
+        Name:       "alice",
+        Age:        31,
+        Tags:       []string{ "a", "b" },
+        Raw:        []byte{ 1, 2, 3 },
+        Address:    &marshalInner { Zip: "10115" },
+        Extra:      map[string]uint16{ "x": 7 },
+        Big:        types.NewUInt128("340282366920938463463374607431768211455", 10).(*types.UInt128),
+        Created:    time.UnixMilli(1554249600123).UTC(),
+        Skip:       "skipped",
+        hidden:     "hidden",
+    }
+
+    data, err := Marshal(origin)
+    if err != nil {
+        t.Fatalf("Marshal failed: %v", err)
+    }
+
+    _, root, err := DeserializeE(data, 0)
+    if err != nil {
+        t.Fatalf("DeserializeE failed: %v", err)
+    }
+    m := root.(*types.Map).Get()
+    for _, key := range []string{ "Skip", "hidden", "score" } {
+        if _, ok := m[key]; ok {
+            t.Errorf("Field %s should not be encoded.", key)
+        }
+    }
+    if _, ok := m["Region"].(*types.String); !ok {
+        t.Error("Embedded field should be inlined.")
+    }
+    if _, ok := m["age"].(*types.Int32); !ok {
+        t.Error("int32 field should be encoded as INT32.")
+    }
+
+    var actual marshalRecord
+    if err := Unmarshal(data, &actual); err != nil {
+        t.Fatalf("Unmarshal failed: %v", err)
+    }
+    origin.Skip = ""
+    origin.hidden = ""
+    if reflect.DeepEqual(actual, origin) {
+        t.Log("Unmarshal test passed.")
+    } else {
+        t.Errorf("Unmarshal test failed: %+v", actual)
+    }
+}
+
+func TestUnmarshal(t *testing.T) {
+    var i8 int8
+    if err := Unmarshal(serializedData["INT32"], &i8); err != nil || i8 != -3 {
+        t.Errorf("Unmarshal into int8 failed: %v", err)
+    }
+
+    var u8 uint8
+    if err := Unmarshal(serializedData["INT32"], &u8); err == nil {
+        t.Error("Unmarshal of a negative number into uint8 should fail.")
+    }
+
+    var samples []float32
+    if err := Unmarshal(serializedData["FLOAT32_ARRAY"], &samples); err != nil || len(samples) != 1 || samples[0] != 0.456 {
+        t.Errorf("Unmarshal of FLOAT32_ARRAY into []float32 failed: %v", err)
+    }
+
+    var s string
+    if err := Unmarshal(serializedData["UINT8"], &s); err == nil {
+        t.Error("Unmarshal of UINT8 into string should fail.")
+    }
+
+    var any interface{}
+    if err := Unmarshal(serializedData["MAP"], &any); err != nil {
+        t.Fatalf("Unmarshal into interface{} failed: %v", err)
+    }
+    expect := map[string]interface{}{ "apple": uint8(2), "banana": false }
+    if !reflect.DeepEqual(any, expect) {
+        t.Errorf("Unmarshal into interface{} failed: %v", any)
+    }
+
+    if err := Unmarshal(serializedData["MAP"], any); err == nil {
+        t.Error("Unmarshal should reject non-pointers.")
+    }
+}
+
+func TestMarshaler(t *testing.T) {
+    origin := marshalPalette { Main: marshalColor { "red" }, Accent: &marshalColor { "blue" } }
+    expect := Serialize(types.NewMap(map[string]types.RootType {
+        "main":     types.NewString("RED"),
+        "accent":   types.NewString("BLUE"),
+    }))
+    t.Run("MARSHAL", testMarshalFunc(origin, expect))
+
+    // Serialize honors Marshaler at any depth.
+    ser := Serialize(types.NewSlice([]types.RootType { marshalColor { "red" } }))
+    if !reflect.DeepEqual(ser, Serialize(types.NewSlice([]types.RootType { types.NewString("RED") }))) {
+        t.Errorf("Serialize should honor Marshaler: % x", ser)
+    }
+
+    var actual marshalPalette
+    if err := Unmarshal(expect, &actual); err != nil || !reflect.DeepEqual(actual, origin) {
+        t.Errorf("Unmarshal should honor Unmarshaler: %+v %v", actual, err)
+    }
+    if err := Unmarshal(serializedData["UINT8"], &actual.Main); err == nil {
+        t.Error("Unmarshal should return the error of UnmarshalBESON.")
+    }
+
+    var merr *MarshalerError
+    if _, err := Marshal(marshalColor{}); !errors.As(err, &merr) {
+        t.Errorf("Marshal should wrap the error of MarshalBESON: %v", err)
+    }
+}
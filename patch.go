@@ -0,0 +1,301 @@
+package beson
+
+import (
+    "bytes"
+    "strconv"
+    "strings"
+
+    "beson/types"
+)
+
+// PatchOp is one operation of a Patch, in the spirit of JSON Patch. Path
+// is a JSON pointer, Value is only used by add and replace.
+type PatchOp struct {
+    Op      string
+    Path    string
+    Value   types.RootType
+}
+
+// Patch is an ordered list of operations turning one document into
+// another.
+type Patch []PatchOp
+
+// Diff returns the operations turning a into b. Values are compared with
+// their type, INT32(1) and INT64(1) differ. Maps are compared entry by
+// entry, arrays element by element at the same index.
+func Diff(a types.RootType, b types.RootType) Patch {
+    var patch Patch
+    diffValue(a, b, "", &patch)
+    return patch
+}
+
+func diffValue(a types.RootType, b types.RootType, path string, patch *Patch) {
+    if isMapValue(a) && isMapValue(b) {
+        aKeys, aValues := mapEntries(a)
+        bKeys, bValues := mapEntries(b)
+        bIndex := make(map[string]int, len(bKeys))
+        for i, key := range bKeys {
+            bIndex[key] = i
+        }
+
+        aIndex := make(map[string]int, len(aKeys))
+        for i, key := range aKeys {
+            aIndex[key] = i
+            if j, ok := bIndex[key]; ok {
+                diffValue(aValues[i], bValues[j], path + "/" + escapePointer(key), patch)
+            } else {
+                *patch = append(*patch, PatchOp { Op: "remove", Path: path + "/" + escapePointer(key) })
+            }
+        }
+        for j, key := range bKeys {
+            if _, ok := aIndex[key]; !ok {
+                *patch = append(*patch, PatchOp { Op: "add", Path: path + "/" + escapePointer(key), Value: bValues[j] })
+            }
+        }
+        return
+    }
+
+    aSlice, aOk := a.(*types.Slice)
+    bSlice, bOk := b.(*types.Slice)
+    if aOk && bOk {
+        aElements, bElements := aSlice.Get(), bSlice.Get()
+        common := len(aElements)
+        if len(bElements) < common {
+            common = len(bElements)
+        }
+        for i := 0; i < common; i++ {
+            diffValue(aElements[i], bElements[i], path + "/" + strconv.Itoa(i), patch)
+        }
+        // Trailing elements are removed from the end so earlier indices
+        // stay valid.
+        for i := len(aElements) - 1; i >= common; i-- {
+            *patch = append(*patch, PatchOp { Op: "remove", Path: path + "/" + strconv.Itoa(i) })
+        }
+        for i := common; i < len(bElements); i++ {
+            *patch = append(*patch, PatchOp { Op: "add", Path: path + "/" + strconv.Itoa(i), Value: bElements[i] })
+        }
+        return
+    }
+
+    if !bytes.Equal(Serialize(a), Serialize(b)) {
+        *patch = append(*patch, PatchOp { Op: "replace", Path: path, Value: b })
+    }
+}
+
+// Apply returns doc with the operations of patch applied in order. doc is
+// left untouched, containers along the modified paths are copied.
+func Apply(doc types.RootType, patch Patch) (types.RootType, error) {
+    for i, op := range patch {
+        tokens, err := parsePointer(op.Path)
+        if err == nil {
+            doc, err = applyOp(doc, tokens, op)
+        }
+        if err != nil {
+            return nil, &PatchError { Index: i, Path: op.Path, Err: err }
+        }
+    }
+    return doc, nil
+}
+
+func applyOp(node types.RootType, tokens []string, op PatchOp) (types.RootType, error) {
+    if len(tokens) == 0 {
+        switch op.Op {
+        case "add", "replace":
+            return op.Value, nil
+        case "remove":
+            return nil, ErrPathNotFound
+        }
+        return nil, ErrInvalidPatch
+    }
+    token, last := tokens[0], len(tokens) == 1
+
+    if isMapValue(node) {
+        keys, values := mapEntries(node)
+        index := -1
+        for i, key := range keys {
+            if key == token {
+                index = i
+                break
+            }
+        }
+
+        switch {
+        case !last:
+            if index < 0 {
+                return nil, ErrPathNotFound
+            }
+            child, err := applyOp(values[index], tokens[1:], op)
+            if err != nil {
+                return nil, err
+            }
+            values[index] = child
+        case op.Op == "add":
+            if index < 0 {
+                keys = append(keys, token)
+                values = append(values, op.Value)
+            } else {
+                values[index] = op.Value
+            }
+        case op.Op == "replace":
+            if index < 0 {
+                return nil, ErrPathNotFound
+            }
+            values[index] = op.Value
+        case op.Op == "remove":
+            if index < 0 {
+                return nil, ErrPathNotFound
+            }
+            keys = append(keys[:index], keys[index + 1:]...)
+            values = append(values[:index], values[index + 1:]...)
+        default:
+            return nil, ErrInvalidPatch
+        }
+        return buildMapLike(node, keys, values), nil
+    }
+
+    slice, ok := node.(*types.Slice)
+    if !ok {
+        return nil, ErrPathNotFound
+    }
+    elements := append([]types.RootType{}, slice.Get()...)
+    index, err := strconv.Atoi(token)
+    if token == "-" {
+        index, err = len(elements), nil
+    }
+    if err != nil || index < 0 || index > len(elements) || (index == len(elements) && !(last && op.Op == "add")) {
+        return nil, ErrPathNotFound
+    }
+
+    switch {
+    case !last:
+        child, err := applyOp(elements[index], tokens[1:], op)
+        if err != nil {
+            return nil, err
+        }
+        elements[index] = child
+    case op.Op == "add":
+        elements = append(elements[:index], append([]types.RootType{ op.Value }, elements[index:]...)...)
+    case op.Op == "replace":
+        elements[index] = op.Value
+    case op.Op == "remove":
+        elements = append(elements[:index], elements[index + 1:]...)
+    default:
+        return nil, ErrInvalidPatch
+    }
+    return types.NewSlice(elements), nil
+}
+
+func isMapValue(value types.RootType) bool {
+    switch value.(type) {
+    case *types.Map, *types.OrderedMap:
+        return true
+    }
+    return false
+}
+
+// buildMapLike builds a map of the same kind as like from its entries.
+func buildMapLike(like types.RootType, keys []string, values []types.RootType) types.RootType {
+    if _, ok := like.(*types.OrderedMap); ok {
+        m := types.NewOrderedMap()
+        for i, key := range keys {
+            m.Set(key, values[i])
+        }
+        return m
+    }
+
+    m := make(map[string]types.RootType, len(keys))
+    for i, key := range keys {
+        m[key] = values[i]
+    }
+    return types.NewMap(m)
+}
+
+// parsePointer splits a JSON pointer into its unescaped reference tokens.
+func parsePointer(path string) ([]string, error) {
+    if path == "" {
+        return nil, nil
+    }
+    if path[0] != '/' {
+        return nil, ErrInvalidPatch
+    }
+
+    tokens := strings.Split(path[1:], "/")
+    for i, token := range tokens {
+        tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+    }
+    return tokens, nil
+}
+
+// ToRootType returns the patch as an array of maps with the keys op, path
+// and, for add and replace, value.
+func (patch Patch) ToRootType() types.RootType {
+    ops := make([]types.RootType, len(patch))
+    for i, op := range patch {
+        m := map[string]types.RootType {
+            "op":   types.NewString(op.Op),
+            "path": types.NewString(op.Path),
+        }
+        if op.Op != "remove" {
+            m["value"] = op.Value
+        }
+        ops[i] = types.NewMap(m)
+    }
+    return types.NewSlice(ops)
+}
+
+// Serialize encodes the patch as beson, see ToRootType.
+func (patch Patch) Serialize() []byte {
+    return Serialize(patch.ToRootType())
+}
+
+// PatchFromRootType is the inverse of Patch.ToRootType.
+func PatchFromRootType(value types.RootType) (Patch, error) {
+    slice, ok := value.(*types.Slice)
+    if !ok {
+        return nil, ErrInvalidPatch
+    }
+
+    patch := make(Patch, 0, len(slice.Get()))
+    for _, element := range slice.Get() {
+        if !isMapValue(element) {
+            return nil, ErrInvalidPatch
+        }
+        keys, values := mapEntries(element)
+        entries := make(map[string]types.RootType, len(keys))
+        for i, key := range keys {
+            entries[key] = values[i]
+        }
+
+        op, opOk := entries["op"].(*types.String)
+        path, pathOk := entries["path"].(*types.String)
+        if !opOk || !pathOk {
+            return nil, ErrInvalidPatch
+        }
+        patchOp := PatchOp { Op: op.Get(), Path: path.Get() }
+        switch patchOp.Op {
+        case "add", "replace":
+            value, ok := entries["value"]
+            if !ok {
+                return nil, ErrInvalidPatch
+            }
+            patchOp.Value = value
+        case "remove":
+        default:
+            return nil, ErrInvalidPatch
+        }
+        patch = append(patch, patchOp)
+    }
+    return patch, nil
+}
+
+// DeserializePatch decodes a patch encoded by Patch.Serialize.
+func DeserializePatch(data []byte) (Patch, error) {
+    end, value, err := DeserializeE(data, 0)
+    if err != nil {
+        return nil, err
+    }
+    if end != uint32(len(data)) {
+        return nil, newDeserializeError(ErrTrailingData, end)
+    }
+    return PatchFromRootType(value)
+}
@@ -0,0 +1,75 @@
+package beson
+
+import (
+    "bytes"
+    "errors"
+    "reflect"
+    "testing"
+
+    "beson/types"
+)
+
+func TestDiff(t *testing.T) {
+    a := types.NewMap(map[string]types.RootType {
+        "count":    types.NewInt32(1),
+        "gone":     types.NewBool(true),
+        "list":     types.NewSlice([]types.RootType { types.NewUInt8(1), types.NewUInt8(2), types.NewUInt8(3) }),
+        "a/b":      types.NewString("x"),
+    })
+    b := types.NewMap(map[string]types.RootType {
+        "count":    types.NewInt64(1),
+        "list":     types.NewSlice([]types.RootType { types.NewUInt8(1), types.NewUInt8(5) }),
+        "a/b":      types.NewString("x"),
+        "new":      nil,
+    })
+
+    patch := Diff(a, b)
+    expect := Patch {
+        { Op: "replace", Path: "/count", Value: types.NewInt64(1) },
+        { Op: "remove", Path: "/gone" },
+        { Op: "replace", Path: "/list/1", Value: types.NewUInt8(5) },
+        { Op: "remove", Path: "/list/2" },
+        { Op: "add", Path: "/new", Value: nil },
+    }
+    if !reflect.DeepEqual(patch, expect) {
+        t.Fatalf("Diff test failed: %+v", patch)
+    }
+
+    actual, err := Apply(a, patch)
+    if err == nil && bytes.Equal(Serialize(actual), Serialize(b)) {
+        t.Log("Apply test passed.")
+    } else {
+        t.Errorf("Apply test failed: %v", err)
+    }
+    if _, ok := a.Get()["gone"]; !ok {
+        t.Error("Apply should leave the original document untouched.")
+    }
+
+    decoded, err := DeserializePatch(patch.Serialize())
+    if err == nil && reflect.DeepEqual(decoded, expect) {
+        t.Log("Patch serialization test passed.")
+    } else {
+        t.Errorf("Patch serialization test failed: %+v %v", decoded, err)
+    }
+
+    if len(Diff(a, a)) != 0 {
+        t.Error("Diff of equal documents should be empty.")
+    }
+}
+
+func TestApplyErrors(t *testing.T) {
+    doc := types.NewSlice([]types.RootType { types.NewUInt8(1) })
+
+    actual, err := Apply(doc, Patch { { Op: "add", Path: "/-", Value: types.NewUInt8(2) }, { Op: "add", Path: "/0", Value: nil } })
+    if err != nil || len(actual.(*types.Slice).Get()) != 3 || actual.(*types.Slice).Get()[0] != nil {
+        t.Errorf("Array insertion failed: %v", err)
+    }
+
+    var perr *PatchError
+    if _, err := Apply(doc, Patch { { Op: "replace", Path: "/3", Value: nil } }); !errors.As(err, &perr) || !errors.Is(err, ErrPathNotFound) {
+        t.Errorf("Out of range index should be rejected: %v", err)
+    }
+    if _, err := Apply(doc, Patch { { Op: "move", Path: "/0" } }); !errors.Is(err, ErrInvalidPatch) {
+        t.Errorf("Unknown operations should be rejected: %v", err)
+    }
+}
@@ -0,0 +1,593 @@
+package beson
+
+import (
+    "errors"
+    "fmt"
+    "math/big"
+    "strconv"
+    "strings"
+
+    "beson/types"
+)
+
+// QueryError reports a malformed query path.
+type QueryError struct {
+    Path    string
+    Offset  int
+    Msg     string
+}
+
+func (e *QueryError) Error() string {
+    return fmt.Sprintf("beson: invalid query %q at %d: %s", e.Path, e.Offset, e.Msg)
+}
+
+// Query returns the values selected by path within data, a decoded tree
+// or an encoded value given as []byte or Raw. Encoded values are walked in
+// place and only the selected values get decoded.
+//
+// Paths follow a JSONPath subset:
+//
+//     $                   the root value
+//     .key  ['key']       a map entry
+//     [3]  [-1]           an array element, negative indices count from the end
+//     .*  [*]             every element of an array or map
+//     [?(@.price > 10)]   the elements of an array or map matching a filter
+//
+// A filter compares a key path below the element with a number, string,
+// true, false or null using ==, !=, <, <=, > or >=, or, without operator,
+// checks the path exists. Missing keys and indices out of range select
+// nothing.
+func Query(data interface{}, path string) ([]types.RootType, error) {
+    steps, err := parseQuery(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var root queryTarget
+    switch value := data.(type) {
+    case []byte:
+        raw, err := Raw(value).Value()
+        if err != nil {
+            return nil, err
+        }
+        root = rawTarget { raw }
+    case Raw:
+        raw, err := value.Value()
+        if err != nil {
+            return nil, err
+        }
+        root = rawTarget { raw }
+    default:
+        root = treeTarget { value }
+    }
+
+    targets, err := evalQuery([]queryTarget{ root }, steps)
+    if err != nil {
+        return nil, err
+    }
+    results := make([]types.RootType, 0, len(targets))
+    for _, target := range targets {
+        value, err := target.value()
+        if err != nil {
+            return nil, err
+        }
+        results = append(results, value)
+    }
+    return results, nil
+}
+
+// QueryOne returns the first value selected by path, or ErrKeyNotFound
+// when there is none.
+func QueryOne(data interface{}, path string) (types.RootType, error) {
+    results, err := Query(data, path)
+    if err != nil {
+        return nil, err
+    }
+    if len(results) == 0 {
+        return nil, ErrKeyNotFound
+    }
+    return results[0], nil
+}
+
+
+/* Evaluation */
+
+// queryTarget abstracts the decoded tree and the raw view. key and index
+// return a nil target when the step selects nothing.
+type queryTarget interface {
+    key(name string) (queryTarget, error)
+    index(i int) (queryTarget, error)
+    children() ([]queryTarget, error)
+    value() (types.RootType, error)
+}
+
+func evalQuery(targets []queryTarget, steps []queryStep) ([]queryTarget, error) {
+    for _, step := range steps {
+        var next []queryTarget
+        for _, target := range targets {
+            selected, err := evalStep(target, step)
+            if err != nil {
+                return nil, err
+            }
+            next = append(next, selected...)
+        }
+        targets = next
+    }
+    return targets, nil
+}
+
+func evalStep(target queryTarget, step queryStep) ([]queryTarget, error) {
+    switch step.kind {
+    case stepKey, stepIndex:
+        var child queryTarget
+        var err error
+        if step.kind == stepKey {
+            child, err = target.key(step.key)
+        } else {
+            child, err = target.index(step.index)
+        }
+        if err != nil || child == nil {
+            return nil, err
+        }
+        return []queryTarget{ child }, nil
+    case stepWildcard:
+        return target.children()
+    }
+
+    children, err := target.children()
+    if err != nil {
+        return nil, err
+    }
+    var selected []queryTarget
+    for _, child := range children {
+        ok, err := step.filter.match(child)
+        if err != nil {
+            return nil, err
+        }
+        if ok {
+            selected = append(selected, child)
+        }
+    }
+    return selected, nil
+}
+
+type queryFilter struct {
+    path    []queryStep
+    op      string
+    operand interface{}
+}
+
+func (f *queryFilter) match(target queryTarget) (bool, error) {
+    found, err := evalQuery([]queryTarget{ target }, f.path)
+    if err != nil || len(found) == 0 {
+        return false, err
+    }
+    if f.op == "" {
+        return true, nil
+    }
+
+    value, err := found[0].value()
+    if err != nil {
+        return false, err
+    }
+    operand, ok := queryScalar(value)
+    if !ok {
+        return false, nil
+    }
+    return compareScalars(operand, f.operand, f.op), nil
+}
+
+// queryScalar maps a value onto the float64, string, bool or nil operands
+// filters compare with.
+func queryScalar(value types.RootType) (interface{}, bool) {
+    switch v := value.(type) {
+    case nil:
+        return nil, true
+    case *types.Bool:
+        return v.Get(), true
+    case *types.String:
+        return v.Get(), true
+    case *types.Float32:
+        return float64(v.Get()), true
+    case *types.Float64:
+        return v.Get(), true
+    case *types.Int8, *types.Int16, *types.Int32, *types.Int64, *types.Int128, *types.Int256:
+        return integerFloat(integerBytes(v), true), true
+    case *types.UInt8, *types.UInt16, *types.UInt32, *types.UInt64, *types.UInt128, *types.UInt256:
+        return integerFloat(integerBytes(v), false), true
+    }
+    return nil, false
+}
+
+func integerFloat(bs []byte, signed bool) float64 {
+    be := make([]byte, len(bs))
+    for i, b := range bs {
+        be[len(bs) - 1 - i] = b
+    }
+    n := new(big.Int).SetBytes(be)
+    if signed && bs[len(bs) - 1] & 0x80 != 0 {
+        n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(bs) * 8)))
+    }
+    f, _ := new(big.Float).SetInt(n).Float64()
+    return f
+}
+
+// compareScalars applies op to operands of the same kind. Operands of
+// different kinds are only ever unequal.
+func compareScalars(a interface{}, b interface{}, op string) bool {
+    var order int
+    switch x := a.(type) {
+    case float64:
+        y, ok := b.(float64)
+        if !ok {
+            return op == "!="
+        }
+        order = compareOrder(x < y, x > y)
+    case string:
+        y, ok := b.(string)
+        if !ok {
+            return op == "!="
+        }
+        order = strings.Compare(x, y)
+    default:
+        if a != b {
+            return op == "!="
+        }
+        return op == "==" || op == "<=" || op == ">="
+    }
+
+    switch op {
+    case "==":
+        return order == 0
+    case "!=":
+        return order != 0
+    case "<":
+        return order < 0
+    case "<=":
+        return order <= 0
+    case ">":
+        return order > 0
+    }
+    return order >= 0
+}
+
+func compareOrder(less bool, greater bool) int {
+    if less {
+        return -1
+    }
+    if greater {
+        return 1
+    }
+    return 0
+}
+
+type treeTarget struct {
+    v   types.RootType
+}
+
+func (t treeTarget) key(name string) (queryTarget, error) {
+    switch m := t.v.(type) {
+    case *types.Map:
+        if value, ok := m.Get()[name]; ok {
+            return treeTarget { value }, nil
+        }
+    case *types.OrderedMap:
+        if value, ok := m.Get(name); ok {
+            return treeTarget { value }, nil
+        }
+    }
+    return nil, nil
+}
+
+func (t treeTarget) index(i int) (queryTarget, error) {
+    slice, ok := t.v.(*types.Slice)
+    if !ok {
+        return nil, nil
+    }
+    elements := slice.Get()
+    if i < 0 {
+        i += len(elements)
+    }
+    if i < 0 || i >= len(elements) {
+        return nil, nil
+    }
+    return treeTarget { elements[i] }, nil
+}
+
+func (t treeTarget) children() ([]queryTarget, error) {
+    var values []types.RootType
+    switch v := t.v.(type) {
+    case *types.Slice:
+        values = v.Get()
+    case *types.Map, *types.OrderedMap:
+        _, values = mapEntries(v)
+    }
+
+    children := make([]queryTarget, len(values))
+    for i, value := range values {
+        children[i] = treeTarget { value }
+    }
+    return children, nil
+}
+
+func (t treeTarget) value() (types.RootType, error) {
+    return t.v, nil
+}
+
+type rawTarget struct {
+    v   RawValue
+}
+
+func (t rawTarget) key(name string) (queryTarget, error) {
+    if _, err := t.v.Map(); err != nil {
+        return nil, nil
+    }
+    value, err := t.v.Lookup(name)
+    if err != nil {
+        if errors.Is(err, ErrKeyNotFound) {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return rawTarget { value }, nil
+}
+
+func (t rawTarget) index(i int) (queryTarget, error) {
+    if t.v.Kind != KindArray && t.v.Kind != KindArrayStart {
+        return nil, nil
+    }
+    children, err := t.children()
+    if err != nil {
+        return nil, err
+    }
+    if i < 0 {
+        i += len(children)
+    }
+    if i < 0 || i >= len(children) {
+        return nil, nil
+    }
+    return children[i], nil
+}
+
+func (t rawTarget) children() ([]queryTarget, error) {
+    switch t.v.Kind {
+    case KindArray, KindArrayStart, KindMap, KindMapStart:
+    default:
+        return nil, nil
+    }
+
+    elements, err := t.v.Elements()
+    if err != nil {
+        return nil, err
+    }
+    children := make([]queryTarget, len(elements))
+    for i, element := range elements {
+        children[i] = rawTarget { element.Value }
+    }
+    return children, nil
+}
+
+func (t rawTarget) value() (types.RootType, error) {
+    return t.v.Decode()
+}
+
+
+/* Parsing */
+
+const (
+    stepKey = iota
+    stepIndex
+    stepWildcard
+    stepFilter
+)
+
+type queryStep struct {
+    kind    int
+    key     string
+    index   int
+    filter  *queryFilter
+}
+
+type queryParser struct {
+    path    string
+    pos     int
+}
+
+func parseQuery(path string) ([]queryStep, error) {
+    p := &queryParser { path: path }
+    if !p.consume("$") {
+        return nil, p.errorf("query must start with $")
+    }
+    steps, err := p.steps(false)
+    if err != nil {
+        return nil, err
+    }
+    if p.pos < len(p.path) {
+        return nil, p.errorf("unexpected %q", p.path[p.pos])
+    }
+    return steps, nil
+}
+
+// steps parses steps up to the end of the path or, within a filter, up to
+// the first character that cannot continue the path.
+func (p *queryParser) steps(relative bool) ([]queryStep, error) {
+    var steps []queryStep
+    for p.pos < len(p.path) {
+        switch p.path[p.pos] {
+        case '.':
+            p.pos++
+            if p.consume("*") {
+                steps = append(steps, queryStep { kind: stepWildcard })
+                continue
+            }
+            name := p.name()
+            if name == "" {
+                return nil, p.errorf("expected a key")
+            }
+            steps = append(steps, queryStep { kind: stepKey, key: name })
+        case '[':
+            p.pos++
+            step, err := p.bracket(relative)
+            if err != nil {
+                return nil, err
+            }
+            steps = append(steps, step)
+        default:
+            return steps, nil
+        }
+    }
+    return steps, nil
+}
+
+func (p *queryParser) bracket(relative bool) (queryStep, error) {
+    var step queryStep
+    switch {
+    case p.consume("*"):
+        step = queryStep { kind: stepWildcard }
+    case p.peek() == '\'' || p.peek() == '"':
+        key, err := p.quoted()
+        if err != nil {
+            return step, err
+        }
+        step = queryStep { kind: stepKey, key: key }
+    case p.consume("?("):
+        filter, err := p.filter()
+        if err != nil {
+            return step, err
+        }
+        step = queryStep { kind: stepFilter, filter: filter }
+    default:
+        start := p.pos
+        for p.pos < len(p.path) && (p.path[p.pos] == '-' || p.path[p.pos] >= '0' && p.path[p.pos] <= '9') {
+            p.pos++
+        }
+        index, err := strconv.Atoi(p.path[start:p.pos])
+        if err != nil {
+            p.pos = start
+            return step, p.errorf("expected an index, a quoted key, * or a filter")
+        }
+        step = queryStep { kind: stepIndex, index: index }
+    }
+
+    if relative && (step.kind == stepWildcard || step.kind == stepFilter) {
+        return step, p.errorf("filters only support keys and indices")
+    }
+    if !p.consume("]") {
+        return step, p.errorf("expected ]")
+    }
+    return step, nil
+}
+
+func (p *queryParser) filter() (*queryFilter, error) {
+    p.spaces()
+    if !p.consume("@") {
+        return nil, p.errorf("filter must start with @")
+    }
+    path, err := p.steps(true)
+    if err != nil {
+        return nil, err
+    }
+    filter := &queryFilter { path: path }
+
+    p.spaces()
+    for _, op := range []string{ "==", "!=", "<=", ">=", "<", ">" } {
+        if p.consume(op) {
+            filter.op = op
+            break
+        }
+    }
+    if filter.op != "" {
+        p.spaces()
+        if filter.operand, err = p.literal(); err != nil {
+            return nil, err
+        }
+        p.spaces()
+    }
+
+    if !p.consume(")") {
+        return nil, p.errorf("expected )")
+    }
+    return filter, nil
+}
+
+func (p *queryParser) literal() (interface{}, error) {
+    switch {
+    case p.peek() == '\'' || p.peek() == '"':
+        return p.quoted()
+    case p.consume("true"):
+        return true, nil
+    case p.consume("false"):
+        return false, nil
+    case p.consume("null"):
+        return nil, nil
+    }
+
+    start := p.pos
+    for p.pos < len(p.path) && strings.IndexByte("+-.0123456789eE", p.path[p.pos]) >= 0 {
+        p.pos++
+    }
+    f, err := strconv.ParseFloat(p.path[start:p.pos], 64)
+    if err != nil {
+        p.pos = start
+        return nil, p.errorf("expected a number, a string, true, false or null")
+    }
+    return f, nil
+}
+
+// quoted parses a string in single or double quotes, a backslash escaping
+// the next character.
+func (p *queryParser) quoted() (string, error) {
+    quote := p.path[p.pos]
+    p.pos++
+
+    var b strings.Builder
+    for p.pos < len(p.path) {
+        c := p.path[p.pos]
+        p.pos++
+        switch {
+        case c == quote:
+            return b.String(), nil
+        case c == '\\' && p.pos < len(p.path):
+            b.WriteByte(p.path[p.pos])
+            p.pos++
+        default:
+            b.WriteByte(c)
+        }
+    }
+    return "", p.errorf("unterminated string")
+}
+
+// name parses an unquoted key, which ends at any character with a meaning
+// in paths or filters.
+func (p *queryParser) name() string {
+    start := p.pos
+    for p.pos < len(p.path) && strings.IndexByte(".[]()=!<> ", p.path[p.pos]) < 0 {
+        p.pos++
+    }
+    return p.path[start:p.pos]
+}
+
+func (p *queryParser) spaces() {
+    for p.pos < len(p.path) && p.path[p.pos] == ' ' {
+        p.pos++
+    }
+}
+
+func (p *queryParser) peek() byte {
+    if p.pos < len(p.path) {
+        return p.path[p.pos]
+    }
+    return 0
+}
+
+func (p *queryParser) consume(token string) bool {
+    if strings.HasPrefix(p.path[p.pos:], token) {
+        p.pos += len(token)
+        return true
+    }
+    return false
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+    return &QueryError { Path: p.path, Offset: p.pos, Msg: fmt.Sprintf(format, args...) }
+}
@@ -0,0 +1,56 @@
+package beson
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+
+    "beson/types"
+)
+
+var queryData = types.NewMap(map[string]types.RootType {
+    "items":    types.NewSlice([]types.RootType {
+        types.NewMap(map[string]types.RootType { "name": types.NewString("pen"), "price": types.NewUInt8(2) }),
+        types.NewMap(map[string]types.RootType { "name": types.NewString("book"), "price": types.NewFloat64(12.5) }),
+        types.NewMap(map[string]types.RootType { "name": types.NewString("bag"), "price": types.NewInt64(40) }),
+        types.NewMap(map[string]types.RootType { "name": types.NewString("gift") }),
+    }),
+    "owner.name":   types.NewString("alice"),
+})
+
+func TestQuery(t *testing.T) {
+    for _, data := range []interface{}{ queryData, Serialize(queryData) } {
+        t.Run("KEY", testQueryFunc(data, "$.items[1].price", types.NewFloat64(12.5)))
+        t.Run("NEGATIVE", testQueryFunc(data, "$.items[-1].name", types.NewString("gift")))
+        t.Run("QUOTED", testQueryFunc(data, "$['owner.name']", types.NewString("alice")))
+        t.Run("WILDCARD", testQueryFunc(data, "$.items[*].price", types.NewUInt8(2), types.NewFloat64(12.5), types.NewInt64(40)))
+        t.Run("FILTER", testQueryFunc(data, "$.items[?(@.price > 10)].name", types.NewString("book"), types.NewString("bag")))
+        t.Run("STRING", testQueryFunc(data, `$.items[?(@.name == "pen")].price`, types.NewUInt8(2)))
+        t.Run("EXISTS", testQueryFunc(data, "$.items[?(@.price)].name", types.NewString("pen"), types.NewString("book"), types.NewString("bag")))
+        t.Run("MISSING", testQueryFunc(data, "$.items[7].price"))
+    }
+}
+
+func testQueryFunc(data interface{}, path string, expect ...types.RootType) func(*testing.T) {
+    return func(t *testing.T) {
+        actual, err := Query(data, path)
+        if err == nil && bytes.Equal(Serialize(types.NewSlice(actual)), Serialize(types.NewSlice(expect))) {
+            t.Log("Query test passed.")
+        } else {
+            t.Errorf("Query test failed: %v %v", actual, err)
+        }
+    }
+}
+
+func TestQueryErrors(t *testing.T) {
+    for _, path := range []string{ "items", "$.items[", "$.items[x]", "$[?(@.a ~ 1)]", "$[?(@[*])]" } {
+        var qerr *QueryError
+        if _, err := Query(queryData, path); !errors.As(err, &qerr) {
+            t.Errorf("Query %q should be rejected: %v", path, err)
+        }
+    }
+
+    if _, err := QueryOne(queryData, "$.owner"); err != ErrKeyNotFound {
+        t.Errorf("QueryOne should report empty results: %v", err)
+    }
+}
@@ -0,0 +1,240 @@
+package beson
+
+import (
+    "strconv"
+
+    "beson/types"
+)
+
+// Raw is a complete encoded value viewed in place. Lookups walk the bytes
+// and skip length prefixed containers without decoding them, nothing is
+// copied until a value is decoded.
+type Raw []byte
+
+// RawValue is one value inside a Raw, its payload still encoded.
+type RawValue struct {
+    Kind    Kind
+    buffer  []byte
+    start   uint32
+}
+
+// RawMap is a RawValue known to hold a MAP or a delimited MAP_START.
+type RawMap struct {
+    value   RawValue
+}
+
+// RawElement is a child of an array or map, Key is empty for arrays.
+type RawElement struct {
+    Key     string
+    Value   RawValue
+}
+
+// Value returns the value held by r, which must span all of r.
+func (r Raw) Value() (RawValue, error) {
+    t, end, err := skipValue(r, 0, 0)
+    if err != nil {
+        return RawValue{}, err
+    }
+    if end != uint32(len(r)) {
+        return RawValue{}, newDeserializeError(ErrTrailingData, end)
+    }
+    return RawValue { Kind: t, buffer: r, start: 2 }, nil
+}
+
+// Lookup returns the value found by following path from the top-level
+// value, see RawValue.Lookup.
+func (r Raw) Lookup(path ...string) (RawValue, error) {
+    value, err := r.Value()
+    if err != nil {
+        return RawValue{}, err
+    }
+    return value.Lookup(path...)
+}
+
+// Map returns the top-level value as a RawMap.
+func (r Raw) Map() (RawMap, error) {
+    value, err := r.Value()
+    if err != nil {
+        return RawMap{}, err
+    }
+    return value.Map()
+}
+
+// Payload returns the encoded bytes following the header, and for map
+// entries the key, of v. It aliases the original buffer.
+func (v RawValue) Payload() []byte {
+    return v.buffer[v.start:]
+}
+
+// Decode builds the value held by v.
+func (v RawValue) Decode() (types.RootType, error) {
+    _, value, err := deserializeData(v.Kind, v.buffer, v.start, newDecodeState(Options{}, v.start))
+    return value, err
+}
+
+// Map returns v as a RawMap, or ErrNotContainer when v is not a map.
+func (v RawValue) Map() (RawMap, error) {
+    if v.Kind != KindMap && v.Kind != KindMapStart {
+        return RawMap{}, newDeserializeError(ErrNotContainer, v.start - 2)
+    }
+    return RawMap { value: v }, nil
+}
+
+// Elements returns the children of an array or map value in wire order.
+func (v RawValue) Elements() ([]RawElement, error) {
+    var elements []RawElement
+    err := eachElement(v, func(element RawElement) bool {
+        elements = append(elements, element)
+        return true
+    })
+    return elements, err
+}
+
+// Lookup follows path from v, each step being a map key or, for arrays,
+// a decimal index. It returns ErrKeyNotFound when a step is missing.
+func (v RawValue) Lookup(path ...string) (RawValue, error) {
+    for _, step := range path {
+        var found *RawValue
+        index := 0
+        err := eachElement(v, func(element RawElement) bool {
+            isArray := v.Kind == KindArray || v.Kind == KindArrayStart
+            if (!isArray && element.Key == step) || (isArray && strconv.Itoa(index) == step) {
+                found = &element.Value
+                return false
+            }
+            index++
+            return true
+        })
+        if err != nil {
+            return RawValue{}, err
+        }
+        if found == nil {
+            return RawValue{}, newDeserializeError(ErrKeyNotFound, v.start)
+        }
+        v = *found
+    }
+    return v, nil
+}
+
+func (m RawMap) Lookup(path ...string) (RawValue, error) {
+    return m.value.Lookup(path...)
+}
+
+func (m RawMap) Elements() ([]RawElement, error) {
+    return m.value.Elements()
+}
+
+// Value returns m as a plain RawValue.
+func (m RawMap) Value() RawValue {
+    return m.value
+}
+
+// eachElement calls fn for every child of the container v, in wire order,
+// until fn returns false.
+func eachElement(v RawValue, fn func(RawElement) bool) error {
+    buffer := v.buffer
+    pos := v.start
+    end := uint32(len(buffer))
+    keyed := v.Kind == KindMap || v.Kind == KindMapStart
+
+    switch v.Kind {
+    case KindArray, KindMap:
+        begin, containerEnd, err := readLengthPrefix(buffer, pos, 4)
+        if err != nil {
+            return err
+        }
+        buffer = buffer[:containerEnd]
+        pos, end = begin, containerEnd
+    case KindArrayStart, KindMapStart:
+        // The payload ends with the end marker.
+        end -= 2
+    default:
+        return newDeserializeError(ErrNotContainer, v.start - 2)
+    }
+
+    for pos < end {
+        payload, t, err := deserializeType(buffer, pos)
+        if err != nil {
+            return err
+        }
+
+        element := RawElement{}
+        if keyed {
+            begin, keyEnd, err := readLengthPrefix(buffer, payload, 2)
+            if err != nil {
+                return err
+            }
+            element.Key = string(buffer[begin:keyEnd])
+            payload = keyEnd
+        }
+        next, err := skipPayload(t, buffer, payload, 0)
+        if err != nil {
+            return err
+        }
+
+        element.Value = RawValue { Kind: t, buffer: buffer[:next], start: payload }
+        if !fn(element) {
+            return nil
+        }
+        pos = next
+    }
+    return nil
+}
+
+// skipValue returns the type of the value whose header sits at start and
+// the offset following it. Keys of map entries are not expected.
+func skipValue(buffer []byte, start uint32, depth int)(Kind, uint32, error) {
+    anchor, t, err := deserializeType(buffer, start)
+    if err != nil {
+        return KindInvalid, start, err
+    }
+    end, err := skipPayload(t, buffer, anchor, depth)
+    return t, end, err
+}
+
+// skipPayload returns the offset following the payload of type t starting
+// at start. Only delimited containers need their children walked.
+func skipPayload(t Kind, buffer []byte, start uint32, depth int)(uint32, error) {
+    if size, ok := payloadSize[t]; ok {
+        if err := checkBounds(buffer, start, size); err != nil {
+            return start, err
+        }
+        return start + size, nil
+    }
+
+    var marker Kind
+    switch t {
+    case KindArrayEnd, KindMapEnd:
+        return start, newDeserializeError(ErrUnexpectedEnd, start - 2)
+    case KindArrayStart:
+        marker = KindArrayEnd
+    case KindMapStart:
+        marker = KindMapEnd
+    default:
+        _, end, err := readLengthPrefix(buffer, start, 4)
+        return end, err
+    }
+
+    if depth >= DefaultDecodeOptions.MaxDepth {
+        return start, newDeserializeError(ErrMaxDepth, start - 2)
+    }
+    pos := start
+    for {
+        anchor, subType, err := deserializeType(buffer, pos)
+        if err != nil {
+            return pos, err
+        }
+        if subType == marker {
+            return anchor, nil
+        }
+        if t == KindMapStart {
+            _, anchor, err = readLengthPrefix(buffer, anchor, 2)
+            if err != nil {
+                return pos, err
+            }
+        }
+        if pos, err = skipPayload(subType, buffer, anchor, depth + 1); err != nil {
+            return pos, err
+        }
+    }
+}
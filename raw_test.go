@@ -0,0 +1,72 @@
+package beson
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+
+    "beson/types"
+)
+
+func TestRawLookup(t *testing.T) {
+    var buf bytes.Buffer
+    enc := NewEncoder(&buf)
+    enc.OpenMap()
+    enc.Key("items")
+    enc.OpenArray()
+    enc.Encode(types.NewString("first"))
+    enc.Encode(types.NewMap(map[string]types.RootType { "price": types.NewFloat64(2.5) }))
+    enc.Close()
+    enc.Key("user")
+    enc.Encode(types.NewMap(map[string]types.RootType {
+        "address":  types.NewMap(map[string]types.RootType { "zip": types.NewString("10115") }),
+        "name":     types.NewString("alice"),
+    }))
+    enc.Close()
+    enc.Flush()
+    raw := Raw(buf.Bytes())
+
+    t.Run("MAP", testRawLookupFunc(raw, []string{ "user", "address", "zip" }, types.NewString("10115")))
+    t.Run("STREAM", testRawLookupFunc(raw, []string{ "items", "1", "price" }, types.NewFloat64(2.5)))
+    t.Run("ROOT", testRawLookupFunc(Raw(serializedData["INT32"]), nil, types.NewInt32(-3)))
+
+    if _, err := raw.Lookup("user", "phone"); !errors.Is(err, ErrKeyNotFound) {
+        t.Errorf("Missing keys should be reported: %v", err)
+    }
+    if _, err := raw.Lookup("user", "name", "first"); !errors.Is(err, ErrNotContainer) {
+        t.Errorf("Stepping into a scalar should be reported: %v", err)
+    }
+
+    m, err := raw.Map()
+    if err != nil {
+        t.Fatalf("Map failed: %v", err)
+    }
+    elements, err := m.Elements()
+    if err != nil || len(elements) != 2 || elements[0].Key != "items" || elements[1].Value.Kind != KindMap {
+        t.Errorf("Elements failed: %+v %v", elements, err)
+    }
+}
+
+func testRawLookupFunc(raw Raw, path []string, expect types.RootType) func(*testing.T) {
+    return func(t *testing.T) {
+        value, err := raw.Lookup(path...)
+        if err != nil {
+            t.Fatalf("Lookup failed: %v", err)
+        }
+        actual, err := value.Decode()
+        if err == nil && bytes.Equal(Serialize(actual), Serialize(expect)) {
+            t.Log("Raw lookup test passed.")
+        } else {
+            t.Errorf("Raw lookup test failed: %v", err)
+        }
+    }
+}
+
+func TestRawMalformed(t *testing.T) {
+    if _, err := Raw(serializedData["STRING"][:8]).Value(); !errors.Is(err, ErrLengthOverflow) {
+        t.Errorf("Truncated values should be reported: %v", err)
+    }
+    if _, err := Raw(append(Serialize(nil), 0)).Value(); !errors.Is(err, ErrTrailingData) {
+        t.Errorf("Trailing data should be reported: %v", err)
+    }
+}
@@ -0,0 +1,139 @@
+package beson
+
+import (
+    "encoding/binary"
+    "reflect"
+    "sync"
+
+    "beson/types"
+)
+
+// Codec converts values of an application type to and from the payload of
+// an extension value.
+type Codec struct {
+    Encode  func(value interface{}) []byte
+    Decode  func(payload []byte) (types.RootType, error)
+}
+
+// Registry binds application types to headers of the extension range,
+// { 0xf0..0xfe, xx }. An extension value is its header, a 4 byte length and
+// the payload produced by its codec, so decoders that do not know the type
+// can still skip it. Built-in types always take precedence over registered
+// ones.
+type Registry struct {
+    mu          sync.RWMutex
+    byType      map[reflect.Type]*extension
+    byKind      map[Kind]*extension
+}
+
+type extension struct {
+    kind    Kind
+    codec   Codec
+}
+
+// DefaultRegistry is used by Serialize, Deserialize and every call whose
+// Options.Registry is nil. It starts empty.
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+    return &Registry {
+        byType:     make(map[reflect.Type]*extension),
+        byKind:     make(map[Kind]*extension),
+    }
+}
+
+// Register binds values of goType to header. The header must lie in the
+// extension range and neither it nor goType may be bound already.
+func (r *Registry) Register(goType reflect.Type, header [2]byte, codec Codec) error {
+    if !isExtensionHeader(header[:]) {
+        return ErrExtensionHeader
+    }
+    if goType == nil || codec.Encode == nil || codec.Decode == nil {
+        return ErrInvalidCodec
+    }
+
+    kind := KindOfHeader(header)
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.byKind[kind] != nil || r.byType[goType] != nil {
+        return ErrDuplicateCodec
+    }
+
+    ext := &extension { kind: kind, codec: codec }
+    r.byType[goType] = ext
+    r.byKind[kind] = ext
+    return nil
+}
+
+// Register binds goType to header in DefaultRegistry.
+func Register(goType reflect.Type, header [2]byte, codec Codec) error {
+    return DefaultRegistry.Register(goType, header, codec)
+}
+
+func (opts Options) registry() *Registry {
+    if opts.Registry == nil {
+        return DefaultRegistry
+    }
+    return opts.Registry
+}
+
+// typeOf is getType falling back to the registered types.
+func (r *Registry) typeOf(data interface{}) Kind {
+    if t := builtinType(data); t != KindInvalid {
+        return t
+    }
+    if ext := r.lookupType(data); ext != nil {
+        return ext.kind
+    }
+    return KindInvalid
+}
+
+func (r *Registry) lookupType(data interface{}) *extension {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    return r.byType[reflect.TypeOf(data)]
+}
+
+func (r *Registry) lookupKind(kind Kind) *extension {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    return r.byKind[kind]
+}
+
+// serializeExtension returns the length prefixed payload of data.
+func (r *Registry) serializeExtension(data interface{}) []byte {
+    ext := r.lookupType(data)
+    if ext == nil {
+        return nil
+    }
+    payload := ext.codec.Encode(data)
+    lengthBytes := make([]byte, 4)
+    binary.LittleEndian.PutUint32(lengthBytes, uint32(len(payload)))
+    return concatBytesArray(lengthBytes, payload)
+}
+
+func deserializeExtension(t Kind, buffer []byte, start uint32, st *decodeState)(uint32, types.RootType, error) {
+    ext := st.opts.registry().lookupKind(t)
+    if ext == nil {
+        return start, nil, newDeserializeError(ErrUnknownType, start - 2)
+    }
+
+    begin, end, err := st.readLimitedLength(t, buffer, start)
+    if err != nil {
+        return start, nil, err
+    }
+    payload := make([]byte, end - begin)
+    copy(payload, buffer[begin:end])
+
+    value, err := ext.codec.Decode(payload)
+    if err != nil {
+        return start, nil, newDeserializeError(err, start - 2)
+    }
+    return end, value, nil
+}
+
+const extensionPrefix = "extension_"
+
+func isExtensionHeader(header []byte) bool {
+    return header[0] >= 0xf0 && header[0] <= 0xfe
+}
@@ -0,0 +1,143 @@
+package beson
+
+import (
+    "bytes"
+    "errors"
+    "reflect"
+    "testing"
+
+    "beson/types"
+)
+
+// point is an application type encoded as two int8 coordinates.
+type point struct {
+    X, Y int8
+}
+
+var pointCodec = Codec {
+    Encode: func(value interface{}) []byte {
+        p := value.(point)
+        return []byte{ byte(p.X), byte(p.Y) }
+    },
+    Decode: func(payload []byte) (types.RootType, error) {
+        if len(payload) != 2 {
+            return nil, ErrInvalidLength
+        }
+        return point { X: int8(payload[0]), Y: int8(payload[1]) }, nil
+    },
+}
+
+// registryID stands for an application type such as a UUID, a byte array
+// Marshal would otherwise write as an ARRAY.
+type registryID [4]byte
+
+var registryIDCodec = Codec {
+    Encode: func(value interface{}) []byte {
+        id := value.(registryID)
+        return id[:]
+    },
+    Decode: func(payload []byte) (types.RootType, error) {
+        var id registryID
+        if len(payload) != len(id) {
+            return nil, ErrInvalidLength
+        }
+        copy(id[:], payload)
+        return id, nil
+    },
+}
+
+func TestRegistry(t *testing.T) {
+    reg := NewRegistry()
+    if err := reg.Register(reflect.TypeOf(point{}), [2]byte{ 0xf0, 0x01 }, pointCodec); err != nil {
+        t.Fatalf("Register failed: %v", err)
+    }
+
+    value := types.NewSlice([]types.RootType { point { X: 1, Y: -1 }, types.NewString("a") })
+    ser := SerializeWithOptions(value, Options { Registry: reg })
+    expect := []byte{ 6, 0, 15, 0, 0, 0, 0xf0, 0x01, 2, 0, 0, 0, 1, 0xff, 5, 0, 1, 0, 0, 0, 'a' }
+    t.Run("SERIALIZE", testRegistrySerializeFunc(ser, expect))
+
+    _, decoded, err := DeserializeWithOptions(ser, 0, Options { Registry: reg })
+    if err != nil || !reflect.DeepEqual(decoded.(*types.Slice).Get()[0], point { X: 1, Y: -1 }) {
+        t.Errorf("Registry decode test failed: %v %v", decoded, err)
+    }
+
+    // The default registry does not know the header but can still skip it.
+    if _, _, err := DeserializeE(ser, 0); !errors.Is(err, ErrUnknownType) {
+        t.Errorf("Unregistered extension should be an unknown type: %v", err)
+    }
+    if elements, err := Raw(ser).Value(); err != nil || elements.Kind != KindArray {
+        t.Errorf("Raw should skip unregistered extensions: %v", err)
+    }
+    if _, err := Explain(ser); !errors.Is(err, ErrUnknownType) {
+        t.Errorf("Explain should reject unregistered extensions: %v", err)
+    }
+
+    limits := DecodeOptions { MaxBinaryLen: 1 }
+    if _, _, err := DeserializeWithOptions(ser, 0, Options { Registry: reg, Limits: &limits }); !errors.Is(err, ErrMaxBinaryLen) {
+        t.Errorf("Extension payloads should obey MaxBinaryLen: %v", err)
+    }
+}
+
+func testRegistrySerializeFunc(ser []byte, expect []byte) func(*testing.T) {
+    return func(t *testing.T) {
+        if bytes.Equal(ser, expect) {
+            t.Log("Registry serialize test passed.")
+        } else {
+            t.Errorf("Registry serialize test failed: % x", ser)
+        }
+    }
+}
+
+func TestRegistryRegister(t *testing.T) {
+    reg := NewRegistry()
+    if err := reg.Register(reflect.TypeOf(point{}), [2]byte{ 0x05, 0x01 }, pointCodec); err != ErrExtensionHeader {
+        t.Errorf("Header outside of the extension range should be rejected: %v", err)
+    }
+    if err := reg.Register(reflect.TypeOf(point{}), [2]byte{ 0xf0, 0x01 }, Codec{}); err != ErrInvalidCodec {
+        t.Errorf("Incomplete codec should be rejected: %v", err)
+    }
+    reg.Register(reflect.TypeOf(point{}), [2]byte{ 0xf0, 0x01 }, pointCodec)
+    if err := reg.Register(reflect.TypeOf(point{}), [2]byte{ 0xf0, 0x02 }, pointCodec); err != ErrDuplicateCodec {
+        t.Errorf("Type registered twice should be rejected: %v", err)
+    }
+    if err := reg.Register(reflect.TypeOf(""), [2]byte{ 0xf0, 0x01 }, pointCodec); err != ErrDuplicateCodec {
+        t.Errorf("Header registered twice should be rejected: %v", err)
+    }
+
+    // The default registry is left untouched.
+    if len(Serialize(point{})) != 0 {
+        t.Errorf("Default registry should not know point")
+    }
+}
+
+func TestRegistryMarshal(t *testing.T) {
+    reg := NewRegistry()
+    if err := reg.Register(reflect.TypeOf(registryID{}), [2]byte{ 0xf0, 0x02 }, registryIDCodec); err != nil {
+        t.Fatalf("Register failed: %v", err)
+    }
+    opts := Options { Registry: reg }
+
+    type record struct {
+        ID      registryID      `beson:"id"`
+        Owner   *registryID     `beson:"owner"`
+    }
+    origin := record { ID: registryID{ 1, 2, 3, 4 }, Owner: &registryID{ 5, 6, 7, 8 } }
+    ser, err := MarshalWithOptions(origin, opts)
+    if err != nil || !bytes.Contains(ser, []byte{ 0xf0, 0x02, 2, 0, 'i', 'd', 4, 0, 0, 0, 1, 2, 3, 4 }) {
+        t.Fatalf("Marshal should write registered struct fields through their codec: % x %v", ser, err)
+    }
+
+    var actual record
+    _, root, err := DeserializeWithOptions(ser, 0, opts)
+    if err == nil {
+        err = FromRootType(root, &actual)
+    }
+    if err != nil || !reflect.DeepEqual(actual, origin) {
+        t.Errorf("Registered struct fields should round trip: %+v %v", actual, err)
+    }
+
+    if ser, _ := Marshal(origin); bytes.Contains(ser, []byte{ 0xf0, 0x02 }) {
+        t.Errorf("Marshal should not see types bound in another registry: % x", ser)
+    }
+}
@@ -4,150 +4,206 @@ import (
     "bytes"
     "encoding/binary"
     "math"
-    "strings"
+    "sort"
 
     "beson/types"
 )
 
 func Serialize(data interface{}) []byte {
-    return serializeContent(data)
+    return serializeContent(data, DefaultRegistry)
 }
 
-func serializeContent(data interface{}) []byte {
-    t := getType(data)
-    typeBuffer := serializeType(t)
-    dataBuffers := serializeData(t, data)
+// SerializeWithOptions is Serialize with encoding options. In canonical
+// mode every integer is written with the smallest width of its signedness
+// that holds the value, so equal documents always produce equal bytes.
+func SerializeWithOptions(data interface{}, opts Options) []byte {
+    if opts.Canonical {
+        data = canonicalize(data)
+    }
+    return serializeContent(data, opts.registry())
+}
 
-    bytesBuffer := bytes.NewBuffer(make([]byte, 0))
-    bytesBuffer.Write(typeBuffer)
-    bytesBuffer.Write(dataBuffers)
+func serializeContent(data interface{}, reg *Registry) []byte {
+    return appendContent(nil, data, reg)
+}
 
-    length := len(typeBuffer) + len(dataBuffers)
-    serialContent := make([]byte, length)
-    bytesBuffer.Read(serialContent)
+// TypeOf returns the DATA_TYPE value data is serialized as, or "" when it
+// is not a supported value. Types of DefaultRegistry are named after their
+// header, e.g. "extension_f001".
+func TypeOf(data interface{}) string {
+    t := getType(data)
+    if t == KindInvalid {
+        return ""
+    }
+    return t.String()
+}
 
-    return serialContent
+func getType(data interface{}) Kind {
+    return DefaultRegistry.typeOf(data)
 }
 
-func getType(data interface{}) string {
-    var t string
+func builtinType(data interface{}) Kind {
+    var t Kind
 
     if data == nil {
-        t = DATA_TYPE["NULL"]
+        t = KindNull
         return t
     }
 
     switch data.(type) {
     case *types.Bool:
         if data.(*types.Bool).Get() {
-            t = DATA_TYPE["TRUE"]
+            t = KindTrue
         } else {
-            t = DATA_TYPE["FALSE"]
+            t = KindFalse
         }
     case *types.Float32:
-        t = DATA_TYPE["FLOAT32"]
+        t = KindFloat32
     case *types.Float64:
-        t = DATA_TYPE["FLOAT64"]
+        t = KindFloat64
     case *types.Int8:
-        t = DATA_TYPE["INT8"]
+        t = KindInt8
     case *types.Int16:
-        t = DATA_TYPE["INT16"]
+        t = KindInt16
     case *types.Int32:
-        t = DATA_TYPE["INT32"]
+        t = KindInt32
     case *types.Int64:
-        t = DATA_TYPE["INT64"]
+        t = KindInt64
     case *types.Int128:
-        t = DATA_TYPE["INT128"]
+        t = KindInt128
+    case *types.Int256:
+        t = KindInt256
     case *types.UInt8:
-        t = DATA_TYPE["UINT8"]
+        t = KindUInt8
     case *types.UInt16:
-        t = DATA_TYPE["UINT16"]
+        t = KindUInt16
     case *types.UInt32:
-        t = DATA_TYPE["UINT32"]
+        t = KindUInt32
     case *types.UInt64:
-        t = DATA_TYPE["UINT64"]
+        t = KindUInt64
     case *types.UInt128:
-        t = DATA_TYPE["UINT128"]
+        t = KindUInt128
+    case *types.UInt256:
+        t = KindUInt256
     case *types.Binary:
-        t = DATA_TYPE["BINARY"]
+        t = KindBinary
+    case *types.SpecialBuffer:
+        t = KindSpecialBuffer
+    case *types.Date:
+        t = KindDate
+    case *types.ObjectId:
+        t = KindObjectId
+    case *types.ArrayBuffer:
+        t = KindArrayBuffer
+    case *types.DataView:
+        t = KindDataView
+    case *types.UInt8Array:
+        t = KindUInt8Array
+    case *types.Int8Array:
+        t = KindInt8Array
+    case *types.UInt16Array:
+        t = KindUInt16Array
+    case *types.Int16Array:
+        t = KindInt16Array
+    case *types.UInt32Array:
+        t = KindUInt32Array
+    case *types.Int32Array:
+        t = KindInt32Array
+    case *types.Float32Array:
+        t = KindFloat32Array
+    case *types.Float64Array:
+        t = KindFloat64Array
     case *types.String:
-        t = DATA_TYPE["STRING"]
+        t = KindString
     case *types.Slice:
-        t = DATA_TYPE["ARRAY"]
-    case *types.Map:
-        t = DATA_TYPE["MAP"]
+        t = KindArray
+    case *types.Map, *types.OrderedMap:
+        t = KindMap
     default:
-        t = ""
+        t = KindInvalid
     }
 
     return t
 }
 
-func serializeType(t string) []byte {
-    typeHeader := make([]byte, 0)
-    if t != "" {
-        t = strings.ToUpper(t)
-        typeHeader = TYPE_HEADER[t]
-    }
-    return typeHeader
+func serializeType(t Kind) []byte {
+    return t.header()
 }
 
-func serializeData(t string, data interface{}) []byte {
+func serializeData(t Kind, data interface{}, reg *Registry) []byte {
     var buffers []byte
 
     switch t {
-    case DATA_TYPE["NULL"]:
+    case KindNull:
         buffers = serializeNull()
-    case DATA_TYPE["TRUE"], DATA_TYPE["FALSE"]:
+    case KindTrue, KindFalse:
         buffers = serializeBoolean()
-    case DATA_TYPE["UINT8"]:
+    case KindUInt8:
         buffers = make([]byte, 1)
         buffers[0] = data.(*types.UInt8).Get()
-    case DATA_TYPE["UINT16"]:
+    case KindUInt16:
         buffers = make([]byte, 2)
         binary.LittleEndian.PutUint16(buffers, data.(*types.UInt16).Get())
-    case DATA_TYPE["UINT32"]:
+    case KindUInt32:
         buffers = make([]byte, 4)
         binary.LittleEndian.PutUint32(buffers, data.(*types.UInt32).Get())
-    case DATA_TYPE["UINT64"]:
+    case KindUInt64:
         buffers = make([]byte, 8)
         binary.LittleEndian.PutUint64(buffers, data.(*types.UInt64).Get())
-    case DATA_TYPE["UINT128"]:
+    case KindUInt128:
         buffers = serializeUInt128(data.(*types.UInt128))
-    case DATA_TYPE["INT8"]:
+    case KindUInt256:
+        buffers = data.(*types.UInt256).ToBytes()
+    case KindInt8:
         buffers = make([]byte, 1)
         buffers[0] = uint8(data.(*types.Int8).Get())
-    case DATA_TYPE["INT16"]:
+    case KindInt16:
         buffers = make([]byte, 2)
         binary.LittleEndian.PutUint16(buffers, uint16(data.(*types.Int16).Get()))
-    case DATA_TYPE["INT32"]:
+    case KindInt32:
         buffers = make([]byte, 4)
         binary.LittleEndian.PutUint32(buffers, uint32(data.(*types.Int32).Get()))
-    case DATA_TYPE["INT64"]:
+    case KindInt64:
         buffers = make([]byte, 8)
         binary.LittleEndian.PutUint64(buffers, uint64(data.(*types.Int64).Get()))
-    case DATA_TYPE["INT128"]:
+    case KindInt128:
         buffers = serializeInt128(data.(*types.Int128))
-    case DATA_TYPE["FLOAT32"]:
+    case KindInt256:
+        buffers = data.(*types.Int256).ToBytes()
+    case KindFloat32:
         bits := math.Float32bits(data.(*types.Float32).Get())
         buffers = make([]byte, 4)
         binary.LittleEndian.PutUint32(buffers, bits)
-    case DATA_TYPE["FLOAT64"]:
+    case KindFloat64:
         bits := math.Float64bits(data.(*types.Float64).Get())
         buffers = make([]byte, 8)
         binary.LittleEndian.PutUint64(buffers, bits)
-    case DATA_TYPE["STRING"]:
+    case KindString:
         s := data.(*types.String)
         buffers = serializeString(s)
-    case DATA_TYPE["ARRAY"]:
+    case KindArray:
         slice := data.(*types.Slice)
-        buffers = serializeSlice(slice)
-    case DATA_TYPE["MAP"]:
-        m := data.(*types.Map)
-        buffers = serializeMap(m)
-    case DATA_TYPE["BINARY"]:
+        buffers = serializeSlice(slice, reg)
+    case KindMap:
+        buffers = serializeMap(data, reg)
+    case KindBinary:
         b := data.(*types.Binary)
         buffers = serializeBinary(b)
+    case KindDate:
+        d := data.(*types.Date)
+        buffers = serializeDate(d)
+    case KindObjectId:
+        buffers = data.(*types.ObjectId).ToBytes()
+    case KindArrayBuffer, KindDataView,
+        KindUInt8Array, KindInt8Array,
+        KindUInt16Array, KindInt16Array,
+        KindUInt32Array, KindInt32Array,
+        KindFloat32Array, KindFloat64Array:
+        buffers = serializeTypedArray(data.(typedArray))
+    case KindSpecialBuffer:
+        buffers = serializeSpecialBuffer(data.(*types.SpecialBuffer))
+    default:
+        buffers = reg.serializeExtension(data)
     }
 
     return buffers
@@ -184,69 +240,96 @@ func serializeString(value *types.String) []byte {
     return buf
 }
 
-func serializeShortString(value *types.String) []byte {
-    str := value.Get()
-    length := len(str)
-    lengthBytes := make([]byte, 2)
-    binary.LittleEndian.PutUint16(lengthBytes, uint16(length))
-    
-    dataBytes := []byte(str)
-    buf := concatBytesArray(lengthBytes, dataBytes)
+// serializeSpecialBuffer writes the length, then the subtype and bytes it
+// counts.
+func serializeSpecialBuffer(value *types.SpecialBuffer) []byte {
+    bs := value.ToBytes()
+    buf := make([]byte, 5 + len(bs))
+    binary.LittleEndian.PutUint32(buf, uint32(1 + len(bs)))
+    buf[4] = value.Subtype()
+    copy(buf[5:], bs)
     return buf
 }
 
-func serializeSlice(value *types.Slice) []byte {
-    slice := value.Get()
-    subBytesBuffer := bytes.NewBuffer(make([]byte, 0))
-    for _, element := range slice {
-        subType := getType(element)
-        subTypeBytes := serializeType(subType)
-        subDataBytes := serializeData(subType, element)
-        subBytesBuffer.Write(subTypeBytes)
-        subBytesBuffer.Write(subDataBytes)
-    }
+func serializeSlice(value *types.Slice, reg *Registry) []byte {
+    return appendSlice(nil, value, reg)
+}
 
-    length := subBytesBuffer.Len()
-    lengthBytes := make([]byte, 4)
-    binary.LittleEndian.PutUint32(lengthBytes, uint32(length))
+func serializeMap(data interface{}, reg *Registry) []byte {
+    return appendMap(nil, data, reg)
+}
 
-    dataBytes := make([]byte, length)
-    subBytesBuffer.Read(dataBytes)
+// sortedKeys returns the keys of m in byte order, the order maps are
+// written in.
+func sortedKeys(m map[string]types.RootType) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
 
-    buf := concatBytesArray(lengthBytes, dataBytes)
-    return buf
+// mapEntries returns the entries of a *types.Map or *types.OrderedMap in
+// the order they are written in.
+func mapEntries(data interface{}) ([]string, []types.RootType) {
+    keys := mapKeys(data)
+    values := make([]types.RootType, len(keys))
+    for i, key := range keys {
+        values[i] = mapValue(data, key)
+    }
+    return keys, values
+}
+
+// mapKeys returns the keys of a *types.Map or *types.OrderedMap in the
+// order they are written in.
+func mapKeys(data interface{}) []string {
+    switch m := data.(type) {
+    case *types.Map:
+        return sortedKeys(m.Get())
+    case *types.OrderedMap:
+        return m.Keys()
+    }
+    return nil
 }
 
-func serializeMap(value *types.Map) []byte {
-    subBytesBuffer := bytes.NewBuffer(make([]byte, 0))
-    m := value.Get()
-    for key, value := range m {
-        // serialize key
-        k := types.NewString(key).(*types.String)
-        keyBytes := serializeShortString(k)
-
-        // serialize value
-        subType := getType(value)
-        subTypeBytes := serializeType(subType)
-        subDataBytes := serializeData(subType, value)
-
-        subBytesBuffer.Write(subTypeBytes)
-        subBytesBuffer.Write(keyBytes)
-        subBytesBuffer.Write(subDataBytes)
+func mapValue(data interface{}, key string) types.RootType {
+    switch m := data.(type) {
+    case *types.Map:
+        return m.Get()[key]
+    case *types.OrderedMap:
+        value, _ := m.Get(key)
+        return value
     }
+    return nil
+}
 
-    length := subBytesBuffer.Len()
+func serializeBinary(value *types.Binary) []byte {
+    dataBytes := value.ToBytes()
+    length := len(dataBytes)
     lengthBytes := make([]byte, 4)
     binary.LittleEndian.PutUint32(lengthBytes, uint32(length))
 
-    dataBytes := make([]byte, length)
-    subBytesBuffer.Read(dataBytes)
-
     buf := concatBytesArray(lengthBytes, dataBytes)
     return buf
 }
 
-func serializeBinary(value *types.Binary) []byte {
+// serializeDate stores milliseconds since the Unix epoch as a FLOAT64, the
+// same representation JavaScript uses for Date values.
+func serializeDate(value *types.Date) []byte {
+    bits := math.Float64bits(float64(value.Millis()))
+    buf := make([]byte, 8)
+    binary.LittleEndian.PutUint64(buf, bits)
+    return buf
+}
+
+// typedArray is implemented by the ArrayBuffer, DataView and typed array
+// wrappers, which all encode to a contiguous little endian block.
+type typedArray interface {
+    ToBytes() []byte
+}
+
+func serializeTypedArray(value typedArray) []byte {
     dataBytes := value.ToBytes()
     length := len(dataBytes)
     lengthBytes := make([]byte, 4)
@@ -0,0 +1,124 @@
+package beson
+
+import (
+    "bytes"
+    "encoding/binary"
+    "testing"
+
+    "beson/types"
+)
+
+func TestAppendSerialize(t *testing.T) {
+    for name, value := range originData {
+        t.Run(name, testAppendSerializeFunc(value))
+    }
+    t.Run("NESTED_MAP", testAppendSerializeFunc(nestedMap(8)))
+}
+
+func testAppendSerializeFunc(value types.RootType) func(*testing.T) {
+    return func(t *testing.T) {
+        prefix := []byte{ 0xaa, 0xbb }
+        ser := AppendSerialize(prefix, value)
+        if bytes.Equal(ser[:2], prefix) && bytes.Equal(ser[2:], Serialize(value)) && bytes.Equal(ser[2:], legacySerialize(value)) {
+            t.Log("AppendSerialize test passed.")
+        } else {
+            t.Errorf("AppendSerialize test failed: % x", ser)
+        }
+    }
+}
+
+func TestAppendSerializeUnsupported(t *testing.T) {
+    value := types.NewMap(map[string]types.RootType {
+        "a":    types.NewUInt8(1),
+        "b":    struct{}{},
+        "c":    types.NewSlice([]types.RootType { struct{}{}, types.NewUInt8(2) }),
+    })
+    expect := []byte{
+        9, 0, 18, 0, 0, 0,
+            3, 4, 1, 0, 'a', 1,
+            6, 0, 1, 0, 'c', 3, 0, 0, 0,
+                3, 4, 2,
+    }
+    ser := AppendSerialize(nil, value)
+    if _, _, err := DeserializeE(ser, 0); err != nil || !bytes.Equal(ser, expect) {
+        t.Errorf("Unsupported values should be left out: % x %v", ser, err)
+    }
+}
+
+func TestAppendSerializeAllocs(t *testing.T) {
+    value := nestedMap(8)
+    buf := AppendSerialize(nil, value)
+    allocs := testing.AllocsPerRun(100, func() {
+        buf = AppendSerialize(buf[:0], value)
+    })
+    // One sorted key list per map.
+    if allocs != 8 {
+        t.Errorf("AppendSerialize into a large enough buffer should allocate once per map: %v", allocs)
+    }
+}
+
+// nestedMap returns depth levels of maps, each holding a few scalars and
+// the next level.
+func nestedMap(depth int) types.RootType {
+    var child types.RootType
+    for i := 0; i < depth; i++ {
+        m := map[string]types.RootType {
+            "id":       types.NewUInt32(uint32(i)),
+            "name":     types.NewString("level"),
+            "score":    types.NewFloat64(0.5),
+            "tags":     types.NewSlice([]types.RootType { types.NewString("a"), types.NewInt8(-1), nil }),
+        }
+        if child != nil {
+            m["child"] = child
+        }
+        child = types.NewMap(m)
+    }
+    return child
+}
+
+// legacySerialize writes containers the way the serializer AppendSerialize
+// replaced did, every container copying the bytes of its children. It is
+// kept to compare output against, not performance.
+func legacySerialize(data types.RootType) []byte {
+    t := getType(data)
+    var payload []byte
+    switch t {
+    case KindArray, KindMap:
+        var children bytes.Buffer
+        if t == KindArray {
+            for _, element := range data.(*types.Slice).Get() {
+                children.Write(legacySerialize(element))
+            }
+        } else {
+            keys, values := mapEntries(data)
+            for i, key := range keys {
+                child := legacySerialize(values[i])
+                children.Write(child[:2])
+                binary.Write(&children, binary.LittleEndian, uint16(len(key)))
+                children.WriteString(key)
+                children.Write(child[2:])
+            }
+        }
+        payload = concatBytesArray(binary.LittleEndian.AppendUint32(nil, uint32(children.Len())), children.Bytes())
+    default:
+        payload = serializeData(t, data, DefaultRegistry)
+    }
+    return concatBytesArray(serializeType(t), payload)
+}
+
+func BenchmarkSerializeNestedMap(b *testing.B) {
+    value := nestedMap(8)
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        Serialize(value)
+    }
+}
+
+func BenchmarkAppendSerializeNestedMap(b *testing.B) {
+    value := nestedMap(8)
+    buf := AppendSerialize(nil, value)
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        buf = AppendSerialize(buf[:0], value)
+    }
+}
@@ -140,8 +140,6 @@ func (bin *Binary) compare(a []byte, b []byte, align bool) int {
         
         return 0;
     }
-
-    return 0
 }
 
 // bug
@@ -86,7 +86,6 @@ func (bin *Binary) ToString(base int) (string, error) {
     default:
         return "", errors.New("Only accepts 2 and 16 representations.")
     }
-    return "", nil
 }
 
 func (bin *Binary) From(segments ...*Binary) *Binary {
@@ -0,0 +1,113 @@
+package types
+
+// Clone returns a deep copy of v: every wrapper is copied and so are the
+// slices and maps they hold, nested containers included. Values of unknown
+// types are returned as is.
+func Clone(v RootType) RootType {
+    switch value := v.(type) {
+    case *Bool:
+        c := *value
+        return &c
+    case *Int8:
+        c := *value
+        return &c
+    case *Int16:
+        c := *value
+        return &c
+    case *Int32:
+        c := *value
+        return &c
+    case *Int64:
+        c := *value
+        return &c
+    case *Int128:
+        c := *value
+        return &c
+    case *Int256:
+        return &Int256 { bs: cloneBytes(value.bs) }
+    case *UInt8:
+        c := *value
+        return &c
+    case *UInt16:
+        c := *value
+        return &c
+    case *UInt32:
+        c := *value
+        return &c
+    case *UInt64:
+        c := *value
+        return &c
+    case *UInt128:
+        c := *value
+        return &c
+    case *UInt256:
+        return &UInt256 { bs: cloneBytes(value.bs) }
+    case *Float32:
+        c := *value
+        return &c
+    case *Float64:
+        c := *value
+        return &c
+    case *String:
+        c := *value
+        return &c
+    case *Date:
+        c := *value
+        return &c
+    case *ObjectId:
+        c := *value
+        return &c
+    case *Binary:
+        return value.Clone()
+    case *SpecialBuffer:
+        return NewSpecialBuffer(value.subtype, cloneBytes(value.bs))
+    case *ArrayBuffer:
+        return &ArrayBuffer { bs: cloneBytes(value.bs) }
+    case *DataView:
+        return &DataView { bs: cloneBytes(value.bs) }
+    case *UInt8Array:
+        return &UInt8Array { value: append([]uint8(nil), value.value...) }
+    case *Int8Array:
+        return &Int8Array { value: append([]int8(nil), value.value...) }
+    case *UInt16Array:
+        return &UInt16Array { value: append([]uint16(nil), value.value...) }
+    case *Int16Array:
+        return &Int16Array { value: append([]int16(nil), value.value...) }
+    case *UInt32Array:
+        return &UInt32Array { value: append([]uint32(nil), value.value...) }
+    case *Int32Array:
+        return &Int32Array { value: append([]int32(nil), value.value...) }
+    case *Float32Array:
+        return &Float32Array { value: append([]float32(nil), value.value...) }
+    case *Float64Array:
+        return &Float64Array { value: append([]float64(nil), value.value...) }
+    case *Slice:
+        slice := make([]RootType, len(value.slice))
+        for i, element := range value.slice {
+            slice[i] = Clone(element)
+        }
+        return &Slice { slice: slice }
+    case *Map:
+        m := make(map[string]RootType, len(value.m))
+        for key, element := range value.m {
+            m[key] = Clone(element)
+        }
+        return &Map { m: m }
+    case *OrderedMap:
+        c := NewOrderedMap()
+        for _, key := range value.keys {
+            c.Set(key, Clone(value.m[key]))
+        }
+        return c
+    }
+    return v
+}
+
+func cloneBytes(bs []byte) []byte {
+    if bs == nil {
+        return nil
+    }
+    c := make([]byte, len(bs))
+    copy(c, bs)
+    return c
+}
@@ -0,0 +1,34 @@
+package types
+
+import (
+    "testing"
+)
+
+func TestClone(t *testing.T) {
+    big := NewInt256("-5", 10)
+    samples := NewInt16Array([]int16{ 1, 2 })
+    inner := NewMap(map[string]RootType { "n": NewUInt128("7", 10) })
+    origin := NewSlice([]RootType { big, samples, inner, NewString("s"), nil })
+
+    clone := Clone(origin).(*Slice)
+    if !Equal(clone, origin, EqualOptions{}) {
+        t.Fatal("Clone should equal its origin.")
+    }
+
+    elements := clone.Get()
+    if elements[0] == big || elements[1] == samples || elements[2] == inner {
+        t.Error("Clone should copy every wrapper.")
+    }
+    elements[1].(*Int16Array).Get()[0] = 9
+    elements[2].(*Map).Get()["n"] = nil
+    if samples.Get()[0] != 1 || inner.Get()["n"] == nil {
+        t.Error("Mutating the clone should leave the origin untouched.")
+    }
+
+    ordered := NewOrderedMap()
+    ordered.Set("b", NewUInt8(1))
+    ordered.Set("a", NewUInt8(2))
+    if keys := Clone(ordered).(*OrderedMap).Keys(); keys[0] != "b" || keys[1] != "a" {
+        t.Errorf("Clone should keep the key order: %v", keys)
+    }
+}
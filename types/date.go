@@ -0,0 +1,40 @@
+package types
+
+import (
+    "time"
+)
+
+// Date is a point in time with millisecond precision, the resolution used
+// by the JavaScript Date object that the DATE wire type mirrors.
+type Date struct {
+    t time.Time
+}
+
+func NewDate(value time.Time) *Date {
+    return newDate(value).(*Date)
+}
+
+func NewDateFromMillis(ms int64) *Date {
+    return newDate(time.UnixMilli(ms)).(*Date)
+}
+
+func newDate(value time.Time) RootType {
+    return &Date { truncateToMillis(value) }
+}
+
+func (value *Date) Get() time.Time {
+    return value.t
+}
+
+func (value *Date) Set(newValue time.Time) {
+    value.t = truncateToMillis(newValue)
+}
+
+// Millis returns the number of milliseconds elapsed since the Unix epoch.
+func (value *Date) Millis() int64 {
+    return value.t.UnixMilli()
+}
+
+func truncateToMillis(t time.Time) time.Time {
+    return time.UnixMilli(t.UnixMilli()).UTC()
+}
@@ -0,0 +1,510 @@
+package types
+
+import (
+    "bytes"
+    "encoding/binary"
+    "hash/fnv"
+    "math"
+    "math/big"
+    "sort"
+)
+
+// EqualOptions relaxes the comparison made by Equal.
+type EqualOptions struct {
+    // IgnoreWidth compares numbers by value, whatever their width,
+    // signedness or whether they are integers or floats.
+    IgnoreWidth bool
+    // NaNEqual makes NaN equal to NaN.
+    NaNEqual    bool
+}
+
+// Equal reports whether a and b hold the same value. By default values
+// must have the same type, INT32(1) differs from INT64(1), and floats
+// follow IEEE 754 equality. Maps are equal when they hold the same entries,
+// *Map and *OrderedMap alike and whatever their order.
+func Equal(a RootType, b RootType, opts EqualOptions) bool {
+    ka, kb := kindOf(a), kindOf(b)
+    if ka == kindUnknown || kb == kindUnknown {
+        return false
+    }
+
+    if isNumberKind(ka) && isNumberKind(kb) {
+        if ka != kb && !opts.IgnoreWidth {
+            return false
+        }
+        fa, nanA := numberValue(a)
+        fb, nanB := numberValue(b)
+        if nanA || nanB {
+            return nanA && nanB && opts.NaNEqual
+        }
+        return fa.Cmp(fb) == 0
+    }
+    if ka != kb {
+        return false
+    }
+
+    switch ka {
+    case kindArray:
+        ea, eb := a.(*Slice).Get(), b.(*Slice).Get()
+        if len(ea) != len(eb) {
+            return false
+        }
+        for i := range ea {
+            if !Equal(ea[i], eb[i], opts) {
+                return false
+            }
+        }
+        return true
+    case kindMap:
+        ma, mb := mapOf(a), mapOf(b)
+        if len(ma) != len(mb) {
+            return false
+        }
+        for key, va := range ma {
+            vb, ok := mb[key]
+            if !ok || !Equal(va, vb, opts) {
+                return false
+            }
+        }
+        return true
+    case kindFloat32Array, kindFloat64Array:
+        // Elements follow the rules of FLOAT32 and FLOAT64 values, not
+        // their bytes: both zeros are equal, NaN only under NaNEqual.
+        ea, eb := floatElements(a), floatElements(b)
+        if len(ea) != len(eb) {
+            return false
+        }
+        for i := range ea {
+            if math.IsNaN(ea[i]) || math.IsNaN(eb[i]) {
+                if !(math.IsNaN(ea[i]) && math.IsNaN(eb[i]) && opts.NaNEqual) {
+                    return false
+                }
+            } else if ea[i] != eb[i] {
+                return false
+            }
+        }
+        return true
+    }
+    return compareSameKind(ka, a, b) == 0
+}
+
+// Compare returns -1, 0 or +1 as a sorts before, with or after b. The order
+// is total: null, bool, numbers, string, binary, date, objectid, typed
+// arrays, arrays, maps and special buffers, in that order. Numbers of any
+// type are ordered by value with NaN first, ties broken by type. Arrays and
+// maps compare element by element, maps in key order. Special buffers
+// compare by subtype, then bytes.
+func Compare(a RootType, b RootType) int {
+    ka, kb := kindOf(a), kindOf(b)
+    ca, cb := classOf(ka), classOf(kb)
+    if ca != cb {
+        return compareInts(ca, cb)
+    }
+
+    if isNumberKind(ka) {
+        fa, nanA := numberValue(a)
+        fb, nanB := numberValue(b)
+        switch {
+        case nanA && !nanB:
+            return -1
+        case nanB && !nanA:
+            return 1
+        case !nanA:
+            if c := fa.Cmp(fb); c != 0 {
+                return c
+            }
+        }
+        return compareInts(ka, kb)
+    }
+    if ka != kb {
+        return compareInts(ka, kb)
+    }
+
+    switch ka {
+    case kindArray:
+        ea, eb := a.(*Slice).Get(), b.(*Slice).Get()
+        for i := 0; i < len(ea) && i < len(eb); i++ {
+            if c := Compare(ea[i], eb[i]); c != 0 {
+                return c
+            }
+        }
+        return compareInts(len(ea), len(eb))
+    case kindMap:
+        ma, mb := mapOf(a), mapOf(b)
+        keysA, keysB := sortedMapKeys(ma), sortedMapKeys(mb)
+        for i := 0; i < len(keysA) && i < len(keysB); i++ {
+            if keysA[i] != keysB[i] {
+                if keysA[i] < keysB[i] {
+                    return -1
+                }
+                return 1
+            }
+            if c := Compare(ma[keysA[i]], mb[keysB[i]]); c != 0 {
+                return c
+            }
+        }
+        return compareInts(len(keysA), len(keysB))
+    }
+    return compareSameKind(ka, a, b)
+}
+
+// Hash returns a hash of v that is stable across processes and consistent
+// with Equal under default options: equal values hash alike.
+func Hash(v RootType) uint64 {
+    h := fnv.New64a()
+    writeHash(h, v)
+    return h.Sum64()
+}
+
+type hashWriter interface {
+    Write(p []byte) (int, error)
+}
+
+func writeHash(h hashWriter, v RootType) {
+    k := kindOf(v)
+    h.Write([]byte{ byte(k) })
+
+    switch k {
+    case kindFloat32, kindFloat64:
+        f, _ := floatOf(v)
+        writeHashFloat(h, f)
+    case kindFloat32Array, kindFloat64Array:
+        elements := floatElements(v)
+        writeHashLength(h, len(elements))
+        for _, f := range elements {
+            writeHashFloat(h, f)
+        }
+    case kindArray:
+        elements := v.(*Slice).Get()
+        writeHashLength(h, len(elements))
+        for _, element := range elements {
+            writeHash(h, element)
+        }
+    case kindMap:
+        m := mapOf(v)
+        keys := sortedMapKeys(m)
+        writeHashLength(h, len(keys))
+        for _, key := range keys {
+            writeHashLength(h, len(key))
+            h.Write([]byte(key))
+            writeHash(h, m[key])
+        }
+    default:
+        bs := valueBytes(k, v)
+        writeHashLength(h, len(bs))
+        h.Write(bs)
+    }
+}
+
+func writeHashFloat(h hashWriter, f float64) {
+    // All NaNs hash alike, and so do both zeros.
+    switch {
+    case math.IsNaN(f):
+        f = math.NaN()
+    case f == 0:
+        f = 0
+    }
+    var bs [8]byte
+    binary.LittleEndian.PutUint64(bs[:], math.Float64bits(f))
+    h.Write(bs[:])
+}
+
+func writeHashLength(h hashWriter, n int) {
+    var bs [8]byte
+    binary.LittleEndian.PutUint64(bs[:], uint64(n))
+    h.Write(bs[:])
+}
+
+
+/* Kinds */
+
+const (
+    kindUnknown = iota
+    kindNull
+    kindBool
+    kindInt8
+    kindInt16
+    kindInt32
+    kindInt64
+    kindInt128
+    kindInt256
+    kindUInt8
+    kindUInt16
+    kindUInt32
+    kindUInt64
+    kindUInt128
+    kindUInt256
+    kindFloat32
+    kindFloat64
+    kindString
+    kindBinary
+    kindDate
+    kindObjectId
+    kindArrayBuffer
+    kindDataView
+    kindUInt8Array
+    kindInt8Array
+    kindUInt16Array
+    kindInt16Array
+    kindUInt32Array
+    kindInt32Array
+    kindFloat32Array
+    kindFloat64Array
+    kindArray
+    kindMap
+    kindSpecialBuffer
+)
+
+func kindOf(v RootType) int {
+    switch v.(type) {
+    case nil:
+        return kindNull
+    case *Bool:
+        return kindBool
+    case *Int8:
+        return kindInt8
+    case *Int16:
+        return kindInt16
+    case *Int32:
+        return kindInt32
+    case *Int64:
+        return kindInt64
+    case *Int128:
+        return kindInt128
+    case *Int256:
+        return kindInt256
+    case *UInt8:
+        return kindUInt8
+    case *UInt16:
+        return kindUInt16
+    case *UInt32:
+        return kindUInt32
+    case *UInt64:
+        return kindUInt64
+    case *UInt128:
+        return kindUInt128
+    case *UInt256:
+        return kindUInt256
+    case *Float32:
+        return kindFloat32
+    case *Float64:
+        return kindFloat64
+    case *String:
+        return kindString
+    case *Binary:
+        return kindBinary
+    case *Date:
+        return kindDate
+    case *ObjectId:
+        return kindObjectId
+    case *ArrayBuffer:
+        return kindArrayBuffer
+    case *DataView:
+        return kindDataView
+    case *UInt8Array:
+        return kindUInt8Array
+    case *Int8Array:
+        return kindInt8Array
+    case *UInt16Array:
+        return kindUInt16Array
+    case *Int16Array:
+        return kindInt16Array
+    case *UInt32Array:
+        return kindUInt32Array
+    case *Int32Array:
+        return kindInt32Array
+    case *Float32Array:
+        return kindFloat32Array
+    case *Float64Array:
+        return kindFloat64Array
+    case *Slice:
+        return kindArray
+    case *Map, *OrderedMap:
+        return kindMap
+    case *SpecialBuffer:
+        return kindSpecialBuffer
+    }
+    return kindUnknown
+}
+
+func isNumberKind(k int) bool {
+    return k >= kindInt8 && k <= kindFloat64
+}
+
+// classOf groups kinds that Compare orders by value.
+func classOf(k int) int {
+    switch {
+    case isNumberKind(k):
+        return kindInt8
+    case k >= kindArrayBuffer && k <= kindFloat64Array:
+        return kindArrayBuffer
+    }
+    return k
+}
+
+// numberValue returns the exact value of a number, or reports a NaN.
+func numberValue(v RootType) (*big.Float, bool) {
+    if f, ok := floatOf(v); ok {
+        if math.IsNaN(f) {
+            return nil, true
+        }
+        return new(big.Float).SetFloat64(f), false
+    }
+
+    var n *big.Int
+    switch value := v.(type) {
+    case *Int8:
+        n = big.NewInt(int64(value.Get()))
+    case *Int16:
+        n = big.NewInt(int64(value.Get()))
+    case *Int32:
+        n = big.NewInt(int64(value.Get()))
+    case *Int64:
+        n = big.NewInt(value.Get())
+    case *UInt8:
+        n = new(big.Int).SetUint64(uint64(value.Get()))
+    case *UInt16:
+        n = new(big.Int).SetUint64(uint64(value.Get()))
+    case *UInt32:
+        n = new(big.Int).SetUint64(uint64(value.Get()))
+    case *UInt64:
+        n = new(big.Int).SetUint64(value.Get())
+    case *Int128:
+        n = bigFromBytes(value.ToBytes(), true)
+    case *Int256:
+        n = bigFromBytes(value.ToBytes(), true)
+    case *UInt128:
+        n = bigFromBytes(value.ToBytes(), false)
+    case *UInt256:
+        n = bigFromBytes(value.ToBytes(), false)
+    }
+    return new(big.Float).SetPrec(256).SetInt(n), false
+}
+
+func floatOf(v RootType) (float64, bool) {
+    switch value := v.(type) {
+    case *Float32:
+        return float64(value.Get()), true
+    case *Float64:
+        return value.Get(), true
+    }
+    return 0, false
+}
+
+// floatElements returns the elements of a Float32Array or Float64Array.
+func floatElements(v RootType) []float64 {
+    switch value := v.(type) {
+    case *Float32Array:
+        elements := make([]float64, len(value.value))
+        for i, f := range value.value {
+            elements[i] = float64(f)
+        }
+        return elements
+    case *Float64Array:
+        return value.value
+    }
+    return nil
+}
+
+// bigFromBytes reads a little endian, optionally two's complement, integer.
+func bigFromBytes(bs []byte, signed bool) *big.Int {
+    be := make([]byte, len(bs))
+    for i, b := range bs {
+        be[len(bs) - 1 - i] = b
+    }
+    n := new(big.Int).SetBytes(be)
+    if signed && len(bs) > 0 && bs[len(bs) - 1] & 0x80 != 0 {
+        n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(bs) * 8)))
+    }
+    return n
+}
+
+// compareSameKind orders two non-container values of kind k.
+func compareSameKind(k int, a RootType, b RootType) int {
+    switch k {
+    case kindNull:
+        return 0
+    case kindBool:
+        return compareInts(boolInt(a.(*Bool).Get()), boolInt(b.(*Bool).Get()))
+    case kindString:
+        sa, sb := a.(*String).Get(), b.(*String).Get()
+        if sa == sb {
+            return 0
+        }
+        if sa < sb {
+            return -1
+        }
+        return 1
+    case kindDate:
+        ma, mb := a.(*Date).Millis(), b.(*Date).Millis()
+        if ma == mb {
+            return 0
+        }
+        if ma < mb {
+            return -1
+        }
+        return 1
+    }
+    return bytes.Compare(valueBytes(k, a), valueBytes(k, b))
+}
+
+// valueBytes returns the bytes a scalar value is compared and hashed by.
+func valueBytes(k int, v RootType) []byte {
+    if isNumberKind(k) {
+        n, _ := numberValue(v)
+        return []byte(n.Text('f', 0))
+    }
+
+    switch value := v.(type) {
+    case *Bool:
+        return []byte{ byte(boolInt(value.Get())) }
+    case *String:
+        return []byte(value.Get())
+    case *Date:
+        var bs [8]byte
+        binary.LittleEndian.PutUint64(bs[:], uint64(value.Millis()))
+        return bs[:]
+    case *Binary:
+        return value.ToBytes()
+    case *ObjectId:
+        return value.ToBytes()
+    case *SpecialBuffer:
+        return append([]byte{ value.subtype }, value.bs...)
+    case interface{ ToBytes() []byte }:
+        return value.ToBytes()
+    }
+    return nil
+}
+
+func mapOf(v RootType) map[string]RootType {
+    if ordered, ok := v.(*OrderedMap); ok {
+        return ordered.m
+    }
+    return v.(*Map).Get()
+}
+
+func sortedMapKeys(m map[string]RootType) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+func boolInt(b bool) int {
+    if b {
+        return 1
+    }
+    return 0
+}
+
+func compareInts(a int, b int) int {
+    if a < b {
+        return -1
+    }
+    if a > b {
+        return 1
+    }
+    return 0
+}
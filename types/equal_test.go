@@ -0,0 +1,82 @@
+package types
+
+import (
+    "math"
+    "sort"
+    "testing"
+)
+
+func TestEqual(t *testing.T) {
+    ordered := NewOrderedMap()
+    ordered.Set("b", NewSlice([]RootType { NewInt32(1), nil }))
+    ordered.Set("a", NewString("x"))
+    m := NewMap(map[string]RootType {
+        "a":    NewString("x"),
+        "b":    NewSlice([]RootType { NewInt32(1), nil }),
+    })
+    nan := NewFloat64(math.NaN())
+
+    t.Run("MAP", testEqualFunc(m, ordered, EqualOptions{}, true))
+    t.Run("WIDTH", testEqualFunc(NewInt32(1), NewInt64(1), EqualOptions{}, false))
+    t.Run("IGNORE_WIDTH", testEqualFunc(NewInt32(1), NewUInt256("1", 10), EqualOptions { IgnoreWidth: true }, true))
+    t.Run("INT_FLOAT", testEqualFunc(NewInt8(-2), NewFloat32(-2), EqualOptions { IgnoreWidth: true }, true))
+    t.Run("NAN", testEqualFunc(nan, nan, EqualOptions{}, false))
+    t.Run("NAN_EQUAL", testEqualFunc(nan, nan, EqualOptions { NaNEqual: true }, true))
+    t.Run("ZERO", testEqualFunc(NewFloat64(0), NewFloat64(math.Copysign(0, -1)), EqualOptions{}, true))
+    t.Run("BINARY", testEqualFunc(NewBinary(0).(*Binary).FromBytes([]byte{ 1 }), NewBinary(0).(*Binary).FromBytes([]byte{ 2 }), EqualOptions{}, false))
+    t.Run("FLOAT_ARRAY_ZERO", testEqualFunc(NewFloat64Array([]float64{ 1, 0 }), NewFloat64Array([]float64{ 1, math.Copysign(0, -1) }), EqualOptions{}, true))
+    t.Run("FLOAT_ARRAY_NAN", testEqualFunc(NewFloat32Array([]float32{ float32(math.NaN()) }), NewFloat32Array([]float32{ float32(math.NaN()) }), EqualOptions{}, false))
+    t.Run("FLOAT_ARRAY_NAN_EQUAL", testEqualFunc(NewFloat32Array([]float32{ float32(math.NaN()) }), NewFloat32Array([]float32{ float32(math.NaN()) }), EqualOptions { NaNEqual: true }, true))
+    t.Run("FLOAT_ARRAY_LENGTH", testEqualFunc(NewFloat64Array([]float64{ 1 }), NewFloat64Array([]float64{ 1, 1 }), EqualOptions{}, false))
+    t.Run("SPECIAL_SUBTYPE", testEqualFunc(NewSpecialBuffer(SPECIAL_SUBTYPE_UUID, []byte{ 1 }), NewSpecialBuffer(SPECIAL_SUBTYPE_MD5, []byte{ 1 }), EqualOptions{}, false))
+}
+
+func testEqualFunc(a RootType, b RootType, opts EqualOptions, expect bool) func(*testing.T) {
+    return func(t *testing.T) {
+        if Equal(a, b, opts) == expect {
+            t.Log("Equal test passed.")
+        } else {
+            t.Error("Equal test failed.")
+        }
+    }
+}
+
+func TestCompare(t *testing.T) {
+    values := []RootType {
+        NewSlice([]RootType { NewUInt8(1) }),
+        NewString("a"),
+        NewInt64(3),
+        NewFloat64(math.NaN()),
+        nil,
+        NewUInt128("2", 10).(*UInt128),
+        NewBool(false),
+        NewFloat32(2.5),
+        NewMap(map[string]RootType{}),
+        NewInt8(3),
+    }
+    sort.Slice(values, func(i, j int) bool { return Compare(values[i], values[j]) < 0 })
+
+    expect := []int{ kindNull, kindBool, kindFloat64, kindUInt128, kindFloat32, kindInt8, kindInt64, kindString, kindArray, kindMap }
+    for i, value := range values {
+        if kindOf(value) != expect[i] {
+            t.Fatalf("Compare test failed at %d: %v", i, value)
+        }
+    }
+    if Compare(NewInt256("-5", 10), NewInt8(-4)) >= 0 || Compare(NewString("b"), NewString("a")) <= 0 {
+        t.Error("Compare test failed.")
+    }
+}
+
+func TestHash(t *testing.T) {
+    a := NewMap(map[string]RootType { "x": NewUInt8(1), "y": NewFloat64(0) })
+    b := NewOrderedMap()
+    b.Set("y", NewFloat64(math.Copysign(0, -1)))
+    b.Set("x", NewUInt8(1))
+
+    if Hash(a) != Hash(b) || Hash(NewFloat32Array([]float32{ 0 })) != Hash(NewFloat32Array([]float32{ float32(math.Copysign(0, -1)) })) {
+        t.Error("Equal values should hash alike.")
+    }
+    if Hash(NewInt32(1)) == Hash(NewInt64(1)) || Hash(NewString("ab")) == Hash(NewSlice([]RootType { NewString("a"), NewString("b") })) {
+        t.Error("Different values should hash apart.")
+    }
+}
@@ -340,5 +340,4 @@ func (value *Int128) toDecimalStringSigned(val *Int128) string {
         
         return x
     }
-    return "0"
 }
@@ -240,7 +240,6 @@ func (value *Int128) ToString(base int) (string, error) {
     default:
         return "", errors.New("Only accepts 2 and 16 representations.")
     }
-    return "", nil
 }
 
 func (value *Int128) ToBytes() []byte {
@@ -62,6 +62,17 @@ func toInt256(value interface{}) RootType {
     return newValue
 }
 
+// Int256FromBytes builds a value from its 32 byte little endian
+// representation, as produced by ToBytes.
+func Int256FromBytes(b []byte) *Int256 {
+    bs := make([]byte, 32)
+    copy(bs, b)
+    newValue := &Int256 {
+        bs: bs,
+    }
+    return newValue
+}
+
 func (value *Int256) Get() []byte {
     bs := make([]byte, 32)
     copy(bs, value.bs)
@@ -218,7 +229,6 @@ func (value *Int256) ToString(base int) (string, error) {
     default:
         return "", errors.New("Only accepts 2 and 16 representations.")
     }
-    return "", nil
 }
 
 func (value *Int256) ToBytes() []byte {
@@ -0,0 +1,125 @@
+package types
+
+import (
+    "crypto/md5"
+    "crypto/rand"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "io"
+    "os"
+    "sync/atomic"
+    "time"
+)
+
+const OBJECTID_SIZE int = 12
+
+// ObjectId is a BSON compatible 12 byte identifier laid out as a 4 byte
+// timestamp, a 3 byte machine id, a 2 byte process id and a 3 byte
+// counter, all big endian.
+type ObjectId struct {
+    bs [OBJECTID_SIZE]byte
+}
+
+var objectIdMachine = readMachineId()
+var objectIdCounter = readRandomUint32()
+
+func NewObjectId() *ObjectId {
+    return newObjectId(time.Now()).(*ObjectId)
+}
+
+func newObjectId(t time.Time) RootType {
+    id := &ObjectId {}
+    binary.BigEndian.PutUint32(id.bs[0:4], uint32(t.Unix()))
+    copy(id.bs[4:7], objectIdMachine[:])
+
+    pid := os.Getpid()
+    id.bs[7] = byte(pid >> 8)
+    id.bs[8] = byte(pid)
+
+    counter := atomic.AddUint32(&objectIdCounter, 1)
+    id.bs[9] = byte(counter >> 16)
+    id.bs[10] = byte(counter >> 8)
+    id.bs[11] = byte(counter)
+    return id
+}
+
+func ObjectIdFromHex(s string) (*ObjectId, error) {
+    if len(s) != OBJECTID_SIZE * 2 {
+        return nil, errors.New("ObjectId hex string must be 24 characters long.")
+    }
+
+    bs, err := hex.DecodeString(s)
+    if err != nil {
+        return nil, errors.New("ObjectId hex string contains invalid characters.")
+    }
+    return ObjectIdFromBytes(bs)
+}
+
+func ObjectIdFromBytes(b []byte) (*ObjectId, error) {
+    if len(b) != OBJECTID_SIZE {
+        return nil, errors.New("ObjectId must be 12 bytes long.")
+    }
+
+    id := &ObjectId {}
+    copy(id.bs[:], b)
+    return id, nil
+}
+
+func (id *ObjectId) Timestamp() time.Time {
+    secs := binary.BigEndian.Uint32(id.bs[0:4])
+    return time.Unix(int64(secs), 0).UTC()
+}
+
+func (id *ObjectId) Machine() []byte {
+    machine := make([]byte, 3)
+    copy(machine, id.bs[4:7])
+    return machine
+}
+
+func (id *ObjectId) Pid() uint16 {
+    return binary.BigEndian.Uint16(id.bs[7:9])
+}
+
+func (id *ObjectId) Counter() uint32 {
+    return uint32(id.bs[9]) << 16 | uint32(id.bs[10]) << 8 | uint32(id.bs[11])
+}
+
+func (id *ObjectId) Hex() string {
+    return hex.EncodeToString(id.bs[:])
+}
+
+func (id *ObjectId) String() string {
+    return "ObjectId(\"" + id.Hex() + "\")"
+}
+
+func (id *ObjectId) ToBytes() []byte {
+    bs := make([]byte, OBJECTID_SIZE)
+    copy(bs, id.bs[:])
+    return bs
+}
+
+// readMachineId derives the machine part from the host name, falling back
+// to random bytes when it cannot be read.
+func readMachineId() [3]byte {
+    var id [3]byte
+    hostname, err := os.Hostname()
+    if err != nil || hostname == "" {
+        if _, err := io.ReadFull(rand.Reader, id[:]); err != nil {
+            panic(errors.New("Cannot read random bytes for ObjectId machine id."))
+        }
+        return id
+    }
+
+    sum := md5.Sum([]byte(hostname))
+    copy(id[:], sum[:3])
+    return id
+}
+
+func readRandomUint32() uint32 {
+    var b [4]byte
+    if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+        panic(errors.New("Cannot read random bytes for ObjectId counter."))
+    }
+    return binary.BigEndian.Uint32(b[:])
+}
@@ -0,0 +1,67 @@
+package types
+
+import (
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestObjectIdFromHex(t *testing.T) {
+    t.Run("valid", testObjectIdFromHexFunc("5ca40b80e4d1a3041f2c3b4a", true))
+    t.Run("short", testObjectIdFromHexFunc("5ca40b80e4d1a3041f2c3b", false))
+    t.Run("invalid", testObjectIdFromHexFunc("5ca40b80e4d1a3041f2c3bzz", false))
+}
+
+func testObjectIdFromHexFunc(s string, valid bool) func(*testing.T) {
+    return func(t *testing.T) {
+        id, err := ObjectIdFromHex(s)
+        if valid && (err != nil || id.Hex() != s) {
+            t.Error("ObjectIdFromHex test failed.")
+        } else if !valid && err == nil {
+            t.Error("ObjectIdFromHex test failed.")
+        } else {
+            t.Log("ObjectIdFromHex test passed.")
+        }
+    }
+}
+
+func TestObjectIdParts(t *testing.T) {
+    id, _ := ObjectIdFromHex("5ca40b80e4d1a3041f2c3b4a")
+    if !id.Timestamp().Equal(time.Unix(1554254720, 0)) {
+        t.Error("Timestamp test failed.")
+    }
+    if id.Pid() != 0x041f || id.Counter() != 0x2c3b4a {
+        t.Error("Pid/Counter test failed.")
+    }
+}
+
+func TestNewObjectId(t *testing.T) {
+    const goroutines = 8
+    const count = 1000
+
+    var mutex sync.Mutex
+    var wg sync.WaitGroup
+    seen := map[string]bool{}
+    before := time.Now().Add(-time.Second)
+
+    for i := 0; i < goroutines; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < count; j++ {
+                id := NewObjectId()
+                mutex.Lock()
+                seen[id.Hex()] = true
+                mutex.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+
+    if len(seen) != goroutines * count {
+        t.Errorf("NewObjectId generated %d unique ids, expect %d.", len(seen), goroutines * count)
+    }
+    if NewObjectId().Timestamp().Before(before) {
+        t.Error("NewObjectId timestamp test failed.")
+    }
+}
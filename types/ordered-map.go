@@ -0,0 +1,67 @@
+package types
+
+// OrderedMap is a string keyed map that remembers the order in which keys
+// were first set. It is encoded as a MAP whose entries follow that order.
+// The zero value is an empty map ready to use.
+type OrderedMap struct {
+    keys []string
+    m map[string]RootType
+}
+
+func NewOrderedMap() *OrderedMap {
+    return newOrderedMap().(*OrderedMap)
+}
+
+func newOrderedMap() RootType {
+    return &OrderedMap { keys: []string{}, m: map[string]RootType{} }
+}
+
+func (value *OrderedMap) Get(key string) (RootType, bool) {
+    v, ok := value.m[key]
+    return v, ok
+}
+
+// Set stores v under key. A new key is appended, an existing one keeps its
+// position.
+func (value *OrderedMap) Set(key string, v RootType) {
+    if value.m == nil {
+        value.m = map[string]RootType{}
+    }
+    if _, ok := value.m[key]; !ok {
+        value.keys = append(value.keys, key)
+    }
+    value.m[key] = v
+}
+
+func (value *OrderedMap) Delete(key string) {
+    if _, ok := value.m[key]; !ok {
+        return
+    }
+    delete(value.m, key)
+    for i, k := range value.keys {
+        if k == key {
+            value.keys = append(value.keys[:i], value.keys[i + 1:]...)
+            break
+        }
+    }
+}
+
+// Keys returns the keys in order. The slice is a copy.
+func (value *OrderedMap) Keys() []string {
+    keys := make([]string, len(value.keys))
+    copy(keys, value.keys)
+    return keys
+}
+
+func (value *OrderedMap) Len() int {
+    return len(value.keys)
+}
+
+// ToMap returns the entries as a plain map, dropping the order.
+func (value *OrderedMap) ToMap() map[string]RootType {
+    m := make(map[string]RootType, len(value.m))
+    for k, v := range value.m {
+        m[k] = v
+    }
+    return m
+}
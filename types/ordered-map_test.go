@@ -0,0 +1,36 @@
+package types
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+    m := NewOrderedMap()
+    m.Set("b", NewUInt8(1))
+    m.Set("a", NewUInt8(2))
+    m.Set("c", NewUInt8(3))
+    m.Set("b", NewUInt8(4))
+
+    if !reflect.DeepEqual(m.Keys(), []string{ "b", "a", "c" }) {
+        t.Errorf("Keys test failed: %v", m.Keys())
+    }
+    if v, ok := m.Get("b"); !ok || v.(*UInt8).Get() != 4 {
+        t.Error("Set test failed.")
+    }
+
+    m.Delete("a")
+    m.Delete("missing")
+    if !reflect.DeepEqual(m.Keys(), []string{ "b", "c" }) || m.Len() != 2 {
+        t.Errorf("Delete test failed: %v", m.Keys())
+    }
+    if _, ok := m.Get("a"); ok {
+        t.Error("Delete test failed.")
+    }
+
+    var zero OrderedMap
+    zero.Set("a", NewUInt8(1))
+    if v, ok := zero.Get("a"); !ok || v.(*UInt8).Get() != 1 || zero.Len() != 1 {
+        t.Error("Zero value Set test failed.")
+    }
+}
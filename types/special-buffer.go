@@ -0,0 +1,38 @@
+package types
+
+// Subtypes of SpecialBuffer. Subtypes from SPECIAL_SUBTYPE_USER on are
+// left to applications, subtypes unknown to a reader are kept as they are.
+const (
+    SPECIAL_SUBTYPE_GENERIC     uint8 = 0x00
+    SPECIAL_SUBTYPE_UUID        uint8 = 0x01
+    SPECIAL_SUBTYPE_MD5         uint8 = 0x02
+    SPECIAL_SUBTYPE_ENCRYPTED   uint8 = 0x03
+    SPECIAL_SUBTYPE_PROTOBUF    uint8 = 0x04
+    SPECIAL_SUBTYPE_USER        uint8 = 0x80
+)
+
+// SpecialBuffer is a binary payload tagged with a subtype telling what the
+// bytes hold, e.g. a UUID or an MD5 digest. It is written as the
+// SPECIAL_BUFFER type, a 4 byte length followed by the subtype and the
+// bytes.
+type SpecialBuffer struct {
+    subtype uint8
+    bs      []byte
+}
+
+func NewSpecialBuffer(subtype uint8, bs []byte) *SpecialBuffer {
+    return &SpecialBuffer { subtype: subtype, bs: bs }
+}
+
+func (buf *SpecialBuffer) Subtype() uint8 {
+    return buf.subtype
+}
+
+func (buf *SpecialBuffer) Size() int {
+    return len(buf.bs)
+}
+
+// ToBytes returns the bytes of the buffer, without the subtype.
+func (buf *SpecialBuffer) ToBytes() []byte {
+    return buf.bs
+}
@@ -0,0 +1,414 @@
+package types
+
+import (
+    "encoding/binary"
+    "math"
+)
+
+// ArrayBuffer and DataView carry raw bytes, the typed arrays carry native
+// slices. All of them travel as one contiguous little endian block.
+
+type ArrayBuffer struct {
+    bs []byte
+}
+
+type DataView struct {
+    bs []byte
+}
+
+type UInt8Array struct {
+    value []uint8
+}
+
+type Int8Array struct {
+    value []int8
+}
+
+type UInt16Array struct {
+    value []uint16
+}
+
+type Int16Array struct {
+    value []int16
+}
+
+type UInt32Array struct {
+    value []uint32
+}
+
+type Int32Array struct {
+    value []int32
+}
+
+type Float32Array struct {
+    value []float32
+}
+
+type Float64Array struct {
+    value []float64
+}
+
+
+/* Type initializer */
+
+func NewArrayBuffer(value []byte) *ArrayBuffer {
+    return newArrayBuffer(value).(*ArrayBuffer)
+}
+
+func NewDataView(value []byte) *DataView {
+    return newDataView(value).(*DataView)
+}
+
+func NewUInt8Array(value []uint8) *UInt8Array {
+    return newUInt8Array(value).(*UInt8Array)
+}
+
+func NewInt8Array(value []int8) *Int8Array {
+    return newInt8Array(value).(*Int8Array)
+}
+
+func NewUInt16Array(value []uint16) *UInt16Array {
+    return newUInt16Array(value).(*UInt16Array)
+}
+
+func NewInt16Array(value []int16) *Int16Array {
+    return newInt16Array(value).(*Int16Array)
+}
+
+func NewUInt32Array(value []uint32) *UInt32Array {
+    return newUInt32Array(value).(*UInt32Array)
+}
+
+func NewInt32Array(value []int32) *Int32Array {
+    return newInt32Array(value).(*Int32Array)
+}
+
+func NewFloat32Array(value []float32) *Float32Array {
+    return newFloat32Array(value).(*Float32Array)
+}
+
+func NewFloat64Array(value []float64) *Float64Array {
+    return newFloat64Array(value).(*Float64Array)
+}
+
+
+/* Initialize type to RootType */
+
+func newArrayBuffer(value []byte) RootType {
+    return &ArrayBuffer { value }
+}
+
+func newDataView(value []byte) RootType {
+    return &DataView { value }
+}
+
+func newUInt8Array(value []uint8) RootType {
+    return &UInt8Array { value }
+}
+
+func newInt8Array(value []int8) RootType {
+    return &Int8Array { value }
+}
+
+func newUInt16Array(value []uint16) RootType {
+    return &UInt16Array { value }
+}
+
+func newInt16Array(value []int16) RootType {
+    return &Int16Array { value }
+}
+
+func newUInt32Array(value []uint32) RootType {
+    return &UInt32Array { value }
+}
+
+func newInt32Array(value []int32) RootType {
+    return &Int32Array { value }
+}
+
+func newFloat32Array(value []float32) RootType {
+    return &Float32Array { value }
+}
+
+func newFloat64Array(value []float64) RootType {
+    return &Float64Array { value }
+}
+
+
+/* Get value */
+
+func (value *ArrayBuffer) Get() []byte {
+    return value.bs
+}
+
+func (value *DataView) Get() []byte {
+    return value.bs
+}
+
+func (value *UInt8Array) Get() []uint8 {
+    return value.value
+}
+
+func (value *Int8Array) Get() []int8 {
+    return value.value
+}
+
+func (value *UInt16Array) Get() []uint16 {
+    return value.value
+}
+
+func (value *Int16Array) Get() []int16 {
+    return value.value
+}
+
+func (value *UInt32Array) Get() []uint32 {
+    return value.value
+}
+
+func (value *Int32Array) Get() []int32 {
+    return value.value
+}
+
+func (value *Float32Array) Get() []float32 {
+    return value.value
+}
+
+func (value *Float64Array) Get() []float64 {
+    return value.value
+}
+
+
+/* Set value */
+
+func (value *ArrayBuffer) Set(newValue []byte) {
+    value.bs = newValue
+}
+
+func (value *DataView) Set(newValue []byte) {
+    value.bs = newValue
+}
+
+func (value *UInt8Array) Set(newValue []uint8) {
+    value.value = newValue
+}
+
+func (value *Int8Array) Set(newValue []int8) {
+    value.value = newValue
+}
+
+func (value *UInt16Array) Set(newValue []uint16) {
+    value.value = newValue
+}
+
+func (value *Int16Array) Set(newValue []int16) {
+    value.value = newValue
+}
+
+func (value *UInt32Array) Set(newValue []uint32) {
+    value.value = newValue
+}
+
+func (value *Int32Array) Set(newValue []int32) {
+    value.value = newValue
+}
+
+func (value *Float32Array) Set(newValue []float32) {
+    value.value = newValue
+}
+
+func (value *Float64Array) Set(newValue []float64) {
+    value.value = newValue
+}
+
+
+/* Number of elements */
+
+func (value *ArrayBuffer) Len() int {
+    return len(value.bs)
+}
+
+func (value *DataView) Len() int {
+    return len(value.bs)
+}
+
+func (value *UInt8Array) Len() int {
+    return len(value.value)
+}
+
+func (value *Int8Array) Len() int {
+    return len(value.value)
+}
+
+func (value *UInt16Array) Len() int {
+    return len(value.value)
+}
+
+func (value *Int16Array) Len() int {
+    return len(value.value)
+}
+
+func (value *UInt32Array) Len() int {
+    return len(value.value)
+}
+
+func (value *Int32Array) Len() int {
+    return len(value.value)
+}
+
+func (value *Float32Array) Len() int {
+    return len(value.value)
+}
+
+func (value *Float64Array) Len() int {
+    return len(value.value)
+}
+
+
+/* Little endian byte representation */
+
+func (value *ArrayBuffer) ToBytes() []byte {
+    return value.bs
+}
+
+func (value *DataView) ToBytes() []byte {
+    return value.bs
+}
+
+func (value *UInt8Array) ToBytes() []byte {
+    bs := make([]byte, len(value.value) * 1)
+    copy(bs, value.value)
+    return bs
+}
+
+func (value *Int8Array) ToBytes() []byte {
+    bs := make([]byte, len(value.value) * 1)
+    for i, v := range value.value {
+        bs[i] = uint8(v)
+    }
+    return bs
+}
+
+func (value *UInt16Array) ToBytes() []byte {
+    bs := make([]byte, len(value.value) * 2)
+    for i, v := range value.value {
+        binary.LittleEndian.PutUint16(bs[i * 2:], v)
+    }
+    return bs
+}
+
+func (value *Int16Array) ToBytes() []byte {
+    bs := make([]byte, len(value.value) * 2)
+    for i, v := range value.value {
+        binary.LittleEndian.PutUint16(bs[i * 2:], uint16(v))
+    }
+    return bs
+}
+
+func (value *UInt32Array) ToBytes() []byte {
+    bs := make([]byte, len(value.value) * 4)
+    for i, v := range value.value {
+        binary.LittleEndian.PutUint32(bs[i * 4:], v)
+    }
+    return bs
+}
+
+func (value *Int32Array) ToBytes() []byte {
+    bs := make([]byte, len(value.value) * 4)
+    for i, v := range value.value {
+        binary.LittleEndian.PutUint32(bs[i * 4:], uint32(v))
+    }
+    return bs
+}
+
+func (value *Float32Array) ToBytes() []byte {
+    bs := make([]byte, len(value.value) * 4)
+    for i, v := range value.value {
+        binary.LittleEndian.PutUint32(bs[i * 4:], math.Float32bits(v))
+    }
+    return bs
+}
+
+func (value *Float64Array) ToBytes() []byte {
+    bs := make([]byte, len(value.value) * 8)
+    for i, v := range value.value {
+        binary.LittleEndian.PutUint64(bs[i * 8:], math.Float64bits(v))
+    }
+    return bs
+}
+
+// The FromBytes constructors copy b and ignore trailing bytes that do not
+// form a whole element.
+
+func ArrayBufferFromBytes(b []byte) *ArrayBuffer {
+    bs := make([]byte, len(b))
+    copy(bs, b)
+    return NewArrayBuffer(bs)
+}
+
+func DataViewFromBytes(b []byte) *DataView {
+    bs := make([]byte, len(b))
+    copy(bs, b)
+    return NewDataView(bs)
+}
+
+func UInt8ArrayFromBytes(b []byte) *UInt8Array {
+    values := make([]uint8, len(b) / 1)
+    copy(values, b)
+    return NewUInt8Array(values)
+}
+
+func Int8ArrayFromBytes(b []byte) *Int8Array {
+    values := make([]int8, len(b) / 1)
+    for i := range values {
+        values[i] = int8(b[i])
+    }
+    return NewInt8Array(values)
+}
+
+func UInt16ArrayFromBytes(b []byte) *UInt16Array {
+    values := make([]uint16, len(b) / 2)
+    for i := range values {
+        values[i] = binary.LittleEndian.Uint16(b[i * 2:])
+    }
+    return NewUInt16Array(values)
+}
+
+func Int16ArrayFromBytes(b []byte) *Int16Array {
+    values := make([]int16, len(b) / 2)
+    for i := range values {
+        values[i] = int16(binary.LittleEndian.Uint16(b[i * 2:]))
+    }
+    return NewInt16Array(values)
+}
+
+func UInt32ArrayFromBytes(b []byte) *UInt32Array {
+    values := make([]uint32, len(b) / 4)
+    for i := range values {
+        values[i] = binary.LittleEndian.Uint32(b[i * 4:])
+    }
+    return NewUInt32Array(values)
+}
+
+func Int32ArrayFromBytes(b []byte) *Int32Array {
+    values := make([]int32, len(b) / 4)
+    for i := range values {
+        values[i] = int32(binary.LittleEndian.Uint32(b[i * 4:]))
+    }
+    return NewInt32Array(values)
+}
+
+func Float32ArrayFromBytes(b []byte) *Float32Array {
+    values := make([]float32, len(b) / 4)
+    for i := range values {
+        values[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i * 4:]))
+    }
+    return NewFloat32Array(values)
+}
+
+func Float64ArrayFromBytes(b []byte) *Float64Array {
+    values := make([]float64, len(b) / 8)
+    for i := range values {
+        values[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[i * 8:]))
+    }
+    return NewFloat64Array(values)
+}
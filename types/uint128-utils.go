@@ -447,7 +447,6 @@ func (value *UInt128) toDecimalString(val *UInt128) string {
         
         return x
     }
-    return "0"
 }
 
 func paddingZero(data string, length int) string {
@@ -25,8 +25,6 @@ func NewUInt128(s string, base int) RootType {
     default:
         return parseDecimalToUint(s)
     }
-
-    return parseDecimalToUint(s)
 }
 
 func ToUInt128(value interface{}) RootType {
@@ -169,7 +167,6 @@ func (value *UInt128) ToString(base int) (string, error) {
     default:
         return "", errors.New("Only accepts 2 and 16 representations.")
     }
-    return "", nil
 }
 
 func (value *UInt128) ToBytes() []byte {
@@ -62,6 +62,17 @@ func toUInt256(value interface{}) RootType {
     return newValue
 }
 
+// UInt256FromBytes builds a value from its 32 byte little endian
+// representation, as produced by ToBytes.
+func UInt256FromBytes(b []byte) *UInt256 {
+    bs := make([]byte, 32)
+    copy(bs, b)
+    newValue := &UInt256 {
+        bs: bs,
+    }
+    return newValue
+}
+
 func (value *UInt256) Get() []byte {
     bs := make([]byte, 32)
     copy(bs, value.bs)
@@ -200,7 +211,6 @@ func (value *UInt256) ToString(base int) (string, error) {
     default:
         return "", errors.New("Only accepts 2 and 16 representations.")
     }
-    return "", nil
 }
 
 func (value *UInt256) ToBytes() []byte {
@@ -0,0 +1,342 @@
+package beson
+
+import (
+    "reflect"
+
+    "beson/types"
+)
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// Unmarshaler is implemented by types that decode their own beson value.
+// UnmarshalBESON is given the decoded value, nil values excepted, which
+// reset the destination like for any other type.
+type Unmarshaler interface {
+    UnmarshalBESON(root types.RootType) error
+}
+
+// Unmarshal decodes the beson value in data and stores it in the value
+// pointed to by v, following the mapping used by Marshal. Decoding into an
+// interface{} produces native Go values ([]interface{} for ARRAY,
+// map[string]interface{} for MAP) and keeps wide integers as wrappers.
+func Unmarshal(data []byte, v interface{}) error {
+    rv := reflect.ValueOf(v)
+    if rv.Kind() != reflect.Ptr || rv.IsNil() {
+        return &InvalidUnmarshalError { Type: reflect.TypeOf(v) }
+    }
+
+    _, root, err := DeserializeE(data, 0)
+    if err != nil {
+        return err
+    }
+    return FromRootType(root, v)
+}
+
+// FromRootType stores a decoded types tree in the value pointed to by v.
+func FromRootType(root types.RootType, v interface{}) error {
+    rv := reflect.ValueOf(v)
+    if rv.Kind() != reflect.Ptr || rv.IsNil() {
+        return &InvalidUnmarshalError { Type: reflect.TypeOf(v) }
+    }
+    return unmarshalValue(root, rv.Elem())
+}
+
+func unmarshalValue(root types.RootType, v reflect.Value) error {
+    if root == nil {
+        v.Set(reflect.Zero(v.Type()))
+        return nil
+    }
+    if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(unmarshalerType) {
+        return v.Addr().Interface().(Unmarshaler).UnmarshalBESON(root)
+    }
+
+    rt := reflect.TypeOf(root)
+    if rt.AssignableTo(v.Type()) && v.Kind() != reflect.Interface {
+        v.Set(reflect.ValueOf(root))
+        return nil
+    }
+    if rt.Kind() == reflect.Ptr && rt.Elem() == v.Type() {
+        v.Set(reflect.ValueOf(root).Elem())
+        return nil
+    }
+
+    switch v.Kind() {
+    case reflect.Interface:
+        if v.NumMethod() != 0 {
+            if !rt.AssignableTo(v.Type()) {
+                return newUnmarshalTypeError(root, v.Type())
+            }
+            v.Set(reflect.ValueOf(root))
+            return nil
+        }
+        native := nativeValue(root)
+        if native == nil {
+            v.Set(reflect.Zero(v.Type()))
+        } else {
+            v.Set(reflect.ValueOf(native))
+        }
+        return nil
+    case reflect.Ptr:
+        if v.IsNil() {
+            v.Set(reflect.New(v.Type().Elem()))
+        }
+        return unmarshalValue(root, v.Elem())
+    }
+
+    switch value := root.(type) {
+    case *types.Bool:
+        if v.Kind() != reflect.Bool {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        v.SetBool(value.Get())
+    case *types.Int8:
+        return setInt(root, int64(value.Get()), v)
+    case *types.Int16:
+        return setInt(root, int64(value.Get()), v)
+    case *types.Int32:
+        return setInt(root, int64(value.Get()), v)
+    case *types.Int64:
+        return setInt(root, value.Get(), v)
+    case *types.UInt8:
+        return setUint(root, uint64(value.Get()), v)
+    case *types.UInt16:
+        return setUint(root, uint64(value.Get()), v)
+    case *types.UInt32:
+        return setUint(root, uint64(value.Get()), v)
+    case *types.UInt64:
+        return setUint(root, value.Get(), v)
+    case *types.Float32:
+        return setFloat(root, float64(value.Get()), v)
+    case *types.Float64:
+        return setFloat(root, value.Get(), v)
+    case *types.String:
+        if v.Kind() != reflect.String {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        v.SetString(value.Get())
+    case *types.Binary:
+        if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Uint8 {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        bs := make([]byte, value.Size())
+        copy(bs, value.ToBytes())
+        v.SetBytes(bs)
+    case *types.Date:
+        if v.Type() != timeType {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        v.Set(reflect.ValueOf(value.Get()))
+    case *types.Slice:
+        return unmarshalSlice(value, v)
+    case *types.Map:
+        return unmarshalMap(value, v)
+    case *types.OrderedMap:
+        return unmarshalMap(types.NewMap(value.ToMap()), v)
+    case *types.ArrayBuffer, *types.DataView,
+        *types.UInt8Array, *types.Int8Array,
+        *types.UInt16Array, *types.Int16Array,
+        *types.UInt32Array, *types.Int32Array,
+        *types.Float32Array, *types.Float64Array:
+        native := reflect.ValueOf(nativeValue(root))
+        if !native.Type().ConvertibleTo(v.Type()) || v.Kind() != reflect.Slice {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        v.Set(native.Convert(v.Type()))
+    default:
+        return newUnmarshalTypeError(root, v.Type())
+    }
+    return nil
+}
+
+func setInt(root types.RootType, n int64, v reflect.Value) error {
+    switch v.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        if v.OverflowInt(n) {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        v.SetInt(n)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        if n < 0 || v.OverflowUint(uint64(n)) {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        v.SetUint(uint64(n))
+    default:
+        return newUnmarshalTypeError(root, v.Type())
+    }
+    return nil
+}
+
+func setUint(root types.RootType, n uint64, v reflect.Value) error {
+    switch v.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        if int64(n) < 0 || v.OverflowInt(int64(n)) {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        v.SetInt(int64(n))
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+        if v.OverflowUint(n) {
+            return newUnmarshalTypeError(root, v.Type())
+        }
+        v.SetUint(n)
+    default:
+        return newUnmarshalTypeError(root, v.Type())
+    }
+    return nil
+}
+
+func setFloat(root types.RootType, n float64, v reflect.Value) error {
+    switch v.Kind() {
+    case reflect.Float32, reflect.Float64:
+        v.SetFloat(n)
+    default:
+        return newUnmarshalTypeError(root, v.Type())
+    }
+    return nil
+}
+
+func unmarshalSlice(value *types.Slice, v reflect.Value) error {
+    slice := value.Get()
+    switch v.Kind() {
+    case reflect.Slice:
+        newSlice := reflect.MakeSlice(v.Type(), len(slice), len(slice))
+        for i, element := range slice {
+            if err := unmarshalValue(element, newSlice.Index(i)); err != nil {
+                return err
+            }
+        }
+        v.Set(newSlice)
+    case reflect.Array:
+        if len(slice) != v.Len() {
+            return newUnmarshalTypeError(value, v.Type())
+        }
+        for i, element := range slice {
+            if err := unmarshalValue(element, v.Index(i)); err != nil {
+                return err
+            }
+        }
+    default:
+        return newUnmarshalTypeError(value, v.Type())
+    }
+    return nil
+}
+
+func unmarshalMap(value *types.Map, v reflect.Value) error {
+    m := value.Get()
+    switch v.Kind() {
+    case reflect.Map:
+        t := v.Type()
+        if t.Key().Kind() != reflect.String {
+            return newUnmarshalTypeError(value, t)
+        }
+        if v.IsNil() {
+            v.Set(reflect.MakeMapWithSize(t, len(m)))
+        }
+        for key, element := range m {
+            elem := reflect.New(t.Elem()).Elem()
+            if err := unmarshalValue(element, elem); err != nil {
+                return err
+            }
+            v.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+        }
+    case reflect.Struct:
+        for _, f := range structFields(v.Type()) {
+            element, ok := m[f.name]
+            if !ok {
+                continue
+            }
+            if err := unmarshalValue(element, allocFieldByIndex(v, f.index)); err != nil {
+                return err
+            }
+        }
+    default:
+        return newUnmarshalTypeError(value, v.Type())
+    }
+    return nil
+}
+
+// allocFieldByIndex walks index like reflect.Value.FieldByIndex, allocating
+// nil embedded pointers on the way.
+func allocFieldByIndex(v reflect.Value, index []int) reflect.Value {
+    for i, x := range index {
+        if i > 0 && v.Kind() == reflect.Ptr {
+            if v.IsNil() {
+                v.Set(reflect.New(v.Type().Elem()))
+            }
+            v = v.Elem()
+        }
+        v = v.Field(x)
+    }
+    return v
+}
+
+// nativeValue converts a decoded value into the plain Go value stored in
+// an interface{}. Types without a native counterpart are returned as is.
+func nativeValue(root types.RootType) interface{} {
+    switch value := root.(type) {
+    case *types.Bool:
+        return value.Get()
+    case *types.Int8:
+        return value.Get()
+    case *types.Int16:
+        return value.Get()
+    case *types.Int32:
+        return value.Get()
+    case *types.Int64:
+        return value.Get()
+    case *types.UInt8:
+        return value.Get()
+    case *types.UInt16:
+        return value.Get()
+    case *types.UInt32:
+        return value.Get()
+    case *types.UInt64:
+        return value.Get()
+    case *types.Float32:
+        return value.Get()
+    case *types.Float64:
+        return value.Get()
+    case *types.String:
+        return value.Get()
+    case *types.Binary:
+        bs := make([]byte, value.Size())
+        copy(bs, value.ToBytes())
+        return bs
+    case *types.Date:
+        return value.Get()
+    case *types.ArrayBuffer:
+        return append([]byte{}, value.Get()...)
+    case *types.DataView:
+        return append([]byte{}, value.Get()...)
+    case *types.UInt8Array:
+        return append([]uint8{}, value.Get()...)
+    case *types.Int8Array:
+        return append([]int8{}, value.Get()...)
+    case *types.UInt16Array:
+        return append([]uint16{}, value.Get()...)
+    case *types.Int16Array:
+        return append([]int16{}, value.Get()...)
+    case *types.UInt32Array:
+        return append([]uint32{}, value.Get()...)
+    case *types.Int32Array:
+        return append([]int32{}, value.Get()...)
+    case *types.Float32Array:
+        return append([]float32{}, value.Get()...)
+    case *types.Float64Array:
+        return append([]float64{}, value.Get()...)
+    case *types.Slice:
+        slice := make([]interface{}, len(value.Get()))
+        for i, element := range value.Get() {
+            slice[i] = nativeValue(element)
+        }
+        return slice
+    case *types.Map:
+        m := make(map[string]interface{}, len(value.Get()))
+        for key, element := range value.Get() {
+            m[key] = nativeValue(element)
+        }
+        return m
+    case *types.OrderedMap:
+        return nativeValue(types.NewMap(value.ToMap()))
+    }
+    return root
+}